@@ -0,0 +1,136 @@
+package actions
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"windows-notification/internal/logger"
+)
+
+// 通知動作名稱
+const (
+	Ack    = "ack"
+	Open   = "open"   // 點擊通知，確認後轉到 arg 指定的網址（可為空）
+	Snooze = "snooze" // arg 為延後時間，例如 10m、1h、tomorrow
+)
+
+// Handler 處理通知上的動作按鈕，arg 為動作的額外參數（可為空）
+type Handler func(action, id, arg string) error
+
+// Server 是只監聽本機的 HTTP 服務，接收 Windows 通知動作按鈕開啟的網址
+type Server struct {
+	listener net.Listener
+	server   *http.Server
+	token    string
+	handler  Handler
+	logger   *logger.Logger
+}
+
+// Start 在 127.0.0.1 的隨機埠啟動動作服務
+func Start(handler Handler, log *logger.Logger) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("無法啟動通知動作服務: %w", err)
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	s := &Server{
+		listener: listener,
+		token:    token,
+		handler:  handler,
+		logger:   log,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/action/", s.handle)
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed && log != nil {
+			log.Errorf("通知動作服務已停止: %v", err)
+		}
+	}()
+
+	if log != nil {
+		log.Debugf("通知動作服務已啟動: %s", listener.Addr())
+	}
+	return s, nil
+}
+
+// randomToken 產生隨機存取權杖
+func randomToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("無法產生權杖: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// URL 返回觸發動作的網址
+func (s *Server) URL(action, id, arg string) string {
+	q := url.Values{}
+	q.Set("id", id)
+	q.Set("token", s.token)
+	if arg != "" {
+		q.Set("arg", arg)
+	}
+	return fmt.Sprintf("http://%s/action/%s?%s", s.listener.Addr(), url.PathEscape(action), q.Encode())
+}
+
+// handle 處理動作請求並回傳可直接關閉的頁面
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if subtle.ConstantTimeCompare([]byte(q.Get("token")), []byte(s.token)) != 1 {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	action := strings.TrimPrefix(r.URL.Path, "/action/")
+	id := q.Get("id")
+	if action == "" || id == "" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	if s.logger != nil {
+		s.logger.Infof("收到通知動作: %s (ID: %s)", action, id)
+	}
+
+	message := "已完成，可以關閉此頁面。"
+	status := http.StatusOK
+	if err := s.handler(action, id, q.Get("arg")); err != nil {
+		if s.logger != nil {
+			s.logger.Errorf("處理通知動作失敗 (%s, ID: %s): %v", action, id, err)
+		}
+		status = http.StatusInternalServerError
+		message = "處理失敗: " + err.Error()
+	} else if action == Open {
+		// 點擊通知後繼續開啟通知本身的網址
+		if u, err := url.Parse(q.Get("arg")); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			http.Redirect(w, r, u.String(), http.StatusFound)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<!DOCTYPE html><html><head><meta charset=\"utf-8\"><title>Notification</title></head>"+
+		"<body><p>%s</p><script>setTimeout(function(){window.close()},1500)</script></body></html>", html.EscapeString(message))
+}
+
+// Close 停止動作服務
+func (s *Server) Close() error {
+	return s.server.Close()
+}
@@ -2,9 +2,14 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"windows-notification/internal/logger"
@@ -12,15 +17,27 @@ import (
 
 // Notification 代表一個通知項目
 type Notification struct {
-	ID         string `json:"id"`
-	Project    string `json:"project"`
-	Title      string `json:"title"`
-	Message    string `json:"message"`
-	Status     string `json:"status"`
-	CreatedAt  string `json:"created_at"`
-	NotifiedAt string `json:"notified_at"`
+	ID         string                 `json:"id"`
+	Project    string                 `json:"project"`
+	Title      string                 `json:"title"`
+	Message    string                 `json:"message"`
+	Type       string                 `json:"type,omitempty"`
+	Priority   string                 `json:"priority"`
+	Repo       string                 `json:"repo,omitempty"`
+	Branch     string                 `json:"branch,omitempty"`
+	ActionURL  string                 `json:"action_url,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	Status     string                 `json:"status"`
+	CreatedAt  string                 `json:"created_at"`
+	NotifiedAt string                 `json:"notified_at"`
 }
 
+// 通知狀態
+const (
+	StatusUnnotified = 0 // 未通知
+	StatusNotified   = 1 // 已通知
+)
+
 // APIResponse 代表 API 的回應格式
 type APIResponse struct {
 	Success bool           `json:"success"`
@@ -29,9 +46,40 @@ type APIResponse struct {
 	Message string         `json:"message"`
 }
 
+// HTTPError 代表非預期的 HTTP 回應狀態
+type HTTPError struct {
+	StatusCode int
+	Message    string // 伺服器回應中的 message（可為空）
+}
+
+func (e *HTTPError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("API 回應錯誤: %d (%s)", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("API 回應錯誤: %d", e.StatusCode)
+}
+
+// IsNotFound 判斷錯誤是否為找不到通知（HTTP 404）
+func IsNotFound(err error) bool {
+	var he *HTTPError
+	return errors.As(err, &he) && he.StatusCode == http.StatusNotFound
+}
+
+// IsPermanent 判斷錯誤是否為重送也不會成功的請求錯誤，例如通知已被刪除（HTTP 404）；
+// 4xx 中的 408 與 429 屬於暫時性錯誤，連線錯誤與 5xx 也都可以稍後重試
+func IsPermanent(err error) bool {
+	var he *HTTPError
+	if !errors.As(err, &he) {
+		return false
+	}
+	return he.StatusCode >= 400 && he.StatusCode < 500 &&
+		he.StatusCode != http.StatusRequestTimeout && he.StatusCode != http.StatusTooManyRequests
+}
+
 // Client 是 API 客戶端
 type Client struct {
 	BaseURL    string
+	APIKey     string // 不為空時以 Authorization: Bearer 送出
 	HTTPClient *http.Client
 	Logger     *logger.Logger
 }
@@ -47,20 +95,20 @@ func NewClientWithLogger(baseURL string, log *logger.Logger) *Client {
 	}
 }
 
-// GetUnnotifiedNotifications 取得未通知的通知列表
-func (c *Client) GetUnnotifiedNotifications(project string) ([]Notification, error) {
-	url := fmt.Sprintf("%s/api/notifications?status=0", c.BaseURL)
-	if project != "" {
-		url += fmt.Sprintf("&project=%s", project)
-	}
+// endpoint 組合 API 網址，避免網域結尾的斜線造成重複
+func (c *Client) endpoint(path string) string {
+	return strings.TrimRight(c.BaseURL, "/") + path
+}
 
-	// Log request
-	startTime := time.Now()
-	if c.Logger != nil {
-		c.Logger.Debugf("API 請求: GET %s", url)
+// do 送出請求並檢查 HTTP 狀態；加上認證標頭，錯誤時嘗試讀出伺服器的 message
+func (c *Client) do(req *http.Request, okCodes ...int) (*http.Response, error) {
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
 	}
+	req.Header.Set("Accept", "application/json")
 
-	resp, err := c.HTTPClient.Get(url)
+	startTime := time.Now()
+	resp, err := c.HTTPClient.Do(req)
 	duration := time.Since(startTime).Milliseconds()
 
 	if err != nil {
@@ -69,15 +117,73 @@ func (c *Client) GetUnnotifiedNotifications(project string) ([]Notification, err
 		}
 		return nil, fmt.Errorf("API 請求失敗: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		if c.Logger != nil {
-			c.Logger.Errorf("API 回應錯誤: HTTP %d (%dms)", resp.StatusCode, duration)
+	for _, code := range okCodes {
+		if resp.StatusCode == code {
+			return resp, nil
 		}
-		return nil, fmt.Errorf("API 回應錯誤: %d", resp.StatusCode)
+	}
+	defer resp.Body.Close()
+
+	if c.Logger != nil {
+		c.Logger.Errorf("API 回應錯誤: HTTP %d (%dms)", resp.StatusCode, duration)
+	}
+	var body struct {
+		Message string `json:"message"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	return nil, &HTTPError{StatusCode: resp.StatusCode, Message: body.Message}
+}
+
+// ListOptions 代表查詢通知列表的條件
+type ListOptions struct {
+	Project string
+	Status  int // StatusUnnotified、StatusNotified，或 StatusAny 代表不篩選
+	Limit   int // 0 使用伺服器預設值
+}
+
+// StatusAny 代表查詢時不篩選狀態
+const StatusAny = -1
+
+// GetUnnotifiedNotifications 取得未通知的通知列表
+func (c *Client) GetUnnotifiedNotifications(project string) ([]Notification, error) {
+	return c.ListNotifications(context.Background(), ListOptions{Project: project, Status: StatusUnnotified})
+}
+
+// ListNotifications 依條件查詢通知列表，伺服器依建立時間由新到舊排序
+func (c *Client) ListNotifications(ctx context.Context, opts ListOptions) ([]Notification, error) {
+	query := url.Values{}
+	if opts.Status != StatusAny {
+		query.Set("status", strconv.Itoa(opts.Status))
+	}
+	if opts.Project != "" {
+		query.Set("project", opts.Project)
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	endpoint := c.endpoint("/api/notifications")
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
 	}
 
+	// Log request
+	startTime := time.Now()
+	if c.Logger != nil {
+		c.Logger.Debugf("API 請求: GET %s", endpoint)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("建立請求失敗: %w", err)
+	}
+	resp, err := c.do(req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	duration := time.Since(startTime).Milliseconds()
+
 	var apiResp APIResponse
 	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
 		if c.Logger != nil {
@@ -101,11 +207,52 @@ func (c *Client) GetUnnotifiedNotifications(project string) ([]Notification, err
 	return apiResp.Data, nil
 }
 
+// GetNotification 取得單一通知，找不到時返回的錯誤可用 IsNotFound 判斷
+func (c *Client) GetNotification(ctx context.Context, id string) (*Notification, error) {
+	endpoint := c.endpoint("/api/notifications/" + url.PathEscape(id))
+
+	startTime := time.Now()
+	if c.Logger != nil {
+		c.Logger.Debugf("API 請求: GET %s", endpoint)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("建立請求失敗: %w", err)
+	}
+	resp, err := c.do(req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	duration := time.Since(startTime).Milliseconds()
+
+	var apiResp itemResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		if c.Logger != nil {
+			c.Logger.Errorf("解析回應失敗 (%dms): %v", duration, err)
+		}
+		return nil, fmt.Errorf("解析回應失敗: %w", err)
+	}
+	if !apiResp.Success {
+		return nil, fmt.Errorf("API 回應失敗: %s", apiResp.Message)
+	}
+	return &apiResp.Data, nil
+}
+
 // UpdateNotificationStatus 更新通知狀態為已通知
 func (c *Client) UpdateNotificationStatus(id string) error {
-	url := fmt.Sprintf("%s/api/notifications/%s/status", c.BaseURL, id)
+	return c.SetStatus(context.Background(), id, StatusNotified)
+}
+
+// SetStatus 設定通知狀態；設為 StatusUnnotified 會讓通知重新回到未通知佇列
+func (c *Client) SetStatus(ctx context.Context, id string, status int) error {
+	if status != StatusUnnotified && status != StatusNotified {
+		return fmt.Errorf("無效的通知狀態: %d", status)
+	}
+	endpoint := c.endpoint("/api/notifications/" + url.PathEscape(id) + "/status")
 
-	payload := map[string]int{"status": 1}
+	payload := map[string]int{"status": status}
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		if c.Logger != nil {
@@ -117,10 +264,10 @@ func (c *Client) UpdateNotificationStatus(id string) error {
 	// Log request
 	startTime := time.Now()
 	if c.Logger != nil {
-		c.Logger.Debugf("API 請求: PATCH %s | Body: %s", url, string(jsonData))
+		c.Logger.Debugf("API 請求: PATCH %s | Body: %s", endpoint, string(jsonData))
 	}
 
-	req, err := http.NewRequest(http.MethodPatch, url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, endpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
 		if c.Logger != nil {
 			c.Logger.Errorf("建立請求失敗: %v", err)
@@ -129,43 +276,106 @@ func (c *Client) UpdateNotificationStatus(id string) error {
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
 	duration := time.Since(startTime).Milliseconds()
 
-	if err != nil {
+	var apiResp APIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
 		if c.Logger != nil {
-			c.Logger.Errorf("API 請求失敗 (%dms): %v", duration, err)
+			c.Logger.Errorf("解析回應失敗 (%dms): %v", duration, err)
 		}
-		return fmt.Errorf("API 請求失敗: %w", err)
+		return fmt.Errorf("解析回應失敗: %w", err)
+	}
+
+	// Log response
+	if c.Logger != nil {
+		c.Logger.Debugf("API 回應: HTTP 200 (%dms) | 成功: %v | 訊息: %s", duration, apiResp.Success, apiResp.Message)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	if !apiResp.Success {
 		if c.Logger != nil {
-			c.Logger.Errorf("API 回應錯誤: HTTP %d (%dms)", resp.StatusCode, duration)
+			c.Logger.Errorf("API 回應失敗: %s", apiResp.Message)
 		}
-		return fmt.Errorf("API 回應錯誤: %d", resp.StatusCode)
+		return fmt.Errorf("API 回應失敗: %s", apiResp.Message)
 	}
 
-	var apiResp APIResponse
+	return nil
+}
+
+// CreateRequest 代表建立通知的請求內容，project 之後的選填欄位為空時不送出
+type CreateRequest struct {
+	Project   string                 `json:"project"`
+	Title     string                 `json:"title"`
+	Message   string                 `json:"message"`
+	Type      string                 `json:"type,omitempty"`
+	Priority  string                 `json:"priority,omitempty"`
+	Repo      string                 `json:"repo,omitempty"`
+	Branch    string                 `json:"branch,omitempty"`
+	ActionURL string                 `json:"action_url,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// itemResponse 代表回傳單一通知的 API 回應
+type itemResponse struct {
+	Success bool         `json:"success"`
+	Data    Notification `json:"data"`
+	Message string       `json:"message"`
+}
+
+// CreateNotification 建立新的通知
+func (c *Client) CreateNotification(req CreateRequest) (*Notification, error) {
+	endpoint := c.endpoint("/api/notifications")
+
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		if c.Logger != nil {
+			c.Logger.Errorf("建立請求失敗: %v", err)
+		}
+		return nil, fmt.Errorf("建立請求失敗: %w", err)
+	}
+
+	// Log request
+	startTime := time.Now()
+	if c.Logger != nil {
+		c.Logger.Debugf("API 請求: POST %s | Body: %s", endpoint, string(jsonData))
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("建立請求失敗: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(httpReq, http.StatusOK, http.StatusCreated)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	duration := time.Since(startTime).Milliseconds()
+
+	var apiResp itemResponse
 	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
 		if c.Logger != nil {
 			c.Logger.Errorf("解析回應失敗 (%dms): %v", duration, err)
 		}
-		return fmt.Errorf("解析回應失敗: %w", err)
+		return nil, fmt.Errorf("解析回應失敗: %w", err)
 	}
 
 	// Log response
 	if c.Logger != nil {
-		c.Logger.Debugf("API 回應: HTTP 200 (%dms) | 成功: %v | 訊息: %s", duration, apiResp.Success, apiResp.Message)
+		c.Logger.Debugf("API 回應: HTTP %d (%dms) | 成功: %v | ID: %s", resp.StatusCode, duration, apiResp.Success, apiResp.Data.ID)
 	}
 
 	if !apiResp.Success {
 		if c.Logger != nil {
 			c.Logger.Errorf("API 回應失敗: %s", apiResp.Message)
 		}
-		return fmt.Errorf("API 回應失敗: %s", apiResp.Message)
+		return nil, fmt.Errorf("API 回應失敗: %s", apiResp.Message)
 	}
 
-	return nil
+	return &apiResp.Data, nil
 }
@@ -0,0 +1,372 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"windows-notification/internal/api"
+	"windows-notification/internal/config"
+	"windows-notification/internal/history"
+	"windows-notification/internal/instance"
+	"windows-notification/internal/ledger"
+	"windows-notification/internal/logger"
+	"windows-notification/internal/search"
+)
+
+// 結束代碼
+const (
+	ExitOK          = 0 // 成功
+	ExitError       = 1 // 執行失敗
+	ExitUsage       = 2 // 參數錯誤
+	ExitNotFound    = 3 // 找不到通知
+	ExitAuth        = 4 // 認證失敗（HTTP 401/403）
+	ExitUnavailable = 5 // 無法連線或伺服器錯誤（HTTP 5xx）
+)
+
+// exitCode 依 API 錯誤類型返回結束代碼，讓腳本可以區分失敗原因
+func exitCode(err error) int {
+	var he *api.HTTPError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &he):
+		switch {
+		case he.StatusCode == http.StatusNotFound:
+			return ExitNotFound
+		case he.StatusCode == http.StatusUnauthorized || he.StatusCode == http.StatusForbidden:
+			return ExitAuth
+		case he.StatusCode >= 500:
+			return ExitUnavailable
+		}
+	case errors.As(err, new(*url.Error)):
+		return ExitUnavailable
+	}
+	return ExitError
+}
+
+// command 代表一個命令列子命令
+type command struct {
+	usage string
+	run   func(args []string, stdout, stderr io.Writer) int
+}
+
+const (
+	searchUsage = "search [-config 檔案] [-limit N] <查詢>"
+	unreadUsage = "unread [-config 檔案] [-profile 名稱] [-show] <ID>..."
+	unackUsage  = "unack [-config 檔案] [-profile 名稱] [-show] <ID>...（同 unread）"
+	ackUsage    = "ack [-config 檔案] [-profile 名稱] <ID>..."
+)
+
+var commands = map[string]command{
+	"run":    {usage: runUsage, run: runMonitor},
+	"send":   {usage: sendUsage, run: runSend},
+	"list":   {usage: listUsage, run: runList},
+	"get":    {usage: getUsage, run: runGet},
+	"tail":   {usage: tailUsage, run: runTail},
+	"unack":  {usage: unackUsage, run: runUnread},
+	"search": {usage: searchUsage, run: runSearch},
+	"unread": {usage: unreadUsage, run: runUnread},
+	"ack":    {usage: ackUsage, run: runAck},
+	"ctl":    {usage: ctlUsage, run: runCtl},
+}
+
+// IsCommand 判斷參數是否為命令列子命令，不是時由 GUI 啟動
+func IsCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	_, ok := commands[args[0]]
+	return ok || args[0] == "help"
+}
+
+// Run 執行命令列子命令並返回結束代碼
+func Run(args []string) int {
+	attachConsole()
+
+	stdout, stderr := io.Writer(os.Stdout), io.Writer(os.Stderr)
+	if len(args) == 0 || args[0] == "help" {
+		printUsage(stdout)
+		return ExitOK
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "未知的命令: %s\n", args[0])
+		printUsage(stderr)
+		return ExitUsage
+	}
+	return cmd.run(args[1:], stdout, stderr)
+}
+
+// printUsage 列出所有子命令
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "用法: windows-notification <命令> [參數]")
+	fmt.Fprintln(w, "不帶命令時開啟 GUI 視窗。")
+	fmt.Fprintln(w)
+	for _, name := range []string{"run", "send", "list", "get", "tail", "ack", "unack", "unread", "search", "ctl"} {
+		fmt.Fprintf(w, "  %s\n", commands[name].usage)
+	}
+}
+
+// newFlagSet 建立子命令的參數解析器，錯誤訊息輸出到 stderr
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// loadConfig 載入設定檔，檔案不存在時使用預設設定
+func loadConfig(path string) (*config.Config, error) {
+	cfg, err := config.Load(path)
+	if os.IsNotExist(err) {
+		return config.Default(), nil
+	}
+	return cfg, err
+}
+
+// loadProfile 載入設定檔並返回指定設定檔的完整設定
+func loadProfile(path, name string, stderr io.Writer) (*config.Config, bool) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "載入設定失敗: %v\n", err)
+		return nil, false
+	}
+	pcfg, err := cfg.ForProfile(name)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return nil, false
+	}
+	return pcfg, true
+}
+
+// profileIDs 在非 default 設定檔的通知 ID 前加上 "名稱/"，供執行中的程式找到對應的引擎
+func profileIDs(name string, ids []string) []string {
+	if name == config.DefaultProfile {
+		return ids
+	}
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = name + "/" + id
+	}
+	return out
+}
+
+// handOff 將命令交給使用相同設定檔的執行中程式，由它的引擎更新投遞記錄與通知歷史，
+// 避免兩個程式同時改寫同一份檔案；handled 為 false 時沒有執行中的程式，
+// 呼叫端持有鎖定直接寫入，完成後呼叫 release，寫入期間啟動的監控會被拒絕
+func handOff(configPath string, args []string, stdout, stderr io.Writer) (release func(), code int, handled bool) {
+	path := instance.Path(configPath)
+	lock, err := instance.Acquire(path)
+	var running *instance.RunningError
+	switch {
+	case errors.As(err, &running):
+		out, err := instance.Forward(path, args)
+		fmt.Fprint(stdout, out)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return nil, ExitError, true
+		}
+		return nil, ExitOK, true
+	case err != nil:
+		fmt.Fprintf(stderr, "警告: %v，無法確認是否有執行中的程式\n", err)
+		return func() {}, ExitOK, false
+	}
+	return func() { lock.Release() }, ExitOK, false
+}
+
+// newClient 依設定建立 API 客戶端，所有子命令共用相同的網域與認證
+func newClient(cfg *config.Config, log *logger.Logger) *api.Client {
+	client := api.NewClientWithLogger(cfg.Domain, log)
+	client.APIKey = cfg.APIKey
+	return client
+}
+
+// runSearch 在本機通知歷史中全文搜尋
+func runSearch(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("search", stderr)
+	configPath := fs.String("config", "config.json", "設定檔路徑")
+	limit := fs.Int("limit", 20, "最多顯示筆數，0 不限制")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "用法: "+searchUsage)
+		return ExitUsage
+	}
+
+	query, err := search.Parse(strings.Join(fs.Args(), " "), time.Now())
+	if err != nil {
+		fmt.Fprintf(stderr, "查詢語法錯誤: %v\n", err)
+		return ExitUsage
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "載入設定失敗: %v\n", err)
+		return ExitError
+	}
+	store, err := history.Open(cfg.DataPath("history.json"), cfg.History)
+	if err != nil {
+		fmt.Fprintf(stderr, "載入通知歷史失敗: %v\n", err)
+		return ExitError
+	}
+
+	results := search.Build(store.Query(history.Filter{})).Search(query, *limit)
+	if len(results) == 0 {
+		fmt.Fprintln(stdout, "沒有符合的通知")
+		return ExitOK
+	}
+	for _, r := range results {
+		n := r.Record.Notification
+		fmt.Fprintf(stdout, "#%s  %s  [%s] %s %s\n", n.ID, r.Record.ReceivedAt.Format("2006-01-02 15:04"), n.Project, n.Priority, r.Record.Outcome)
+		fmt.Fprintf(stdout, "    %s\n", search.Plain(r.Title, "*"))
+		if snippet := search.Plain(r.Snippet, "*"); snippet != "" {
+			fmt.Fprintf(stdout, "    %s\n", snippet)
+		}
+	}
+	return ExitOK
+}
+
+// runUnread 將通知在伺服器上改回未通知；預設本機不會再顯示，-show 時下次查詢重新顯示。
+// 有執行中的程式時交給它處理
+func runUnread(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("unread", stderr)
+	configPath := fs.String("config", "config.json", "設定檔路徑")
+	profile := fs.String("profile", config.DefaultProfile, "通知所屬的設定檔")
+	showAgain := fs.Bool("show", false, "本機下次查詢時重新顯示")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "用法: "+unreadUsage)
+		return ExitUsage
+	}
+
+	cfg, ok := loadProfile(*configPath, *profile, stderr)
+	if !ok {
+		return ExitError
+	}
+	release, code, handled := handOff(*configPath, instance.UnreadArgs(profileIDs(*profile, fs.Args()), *showAgain), stdout, stderr)
+	if handled {
+		return code
+	}
+	defer release()
+
+	deliveries, err := ledger.Open(cfg.DataPath("ledger.json"))
+	if err != nil {
+		fmt.Fprintf(stderr, "載入投遞記錄失敗: %v\n", err)
+		return ExitError
+	}
+	store, err := history.Open(cfg.DataPath("history.json"), cfg.History)
+	if err != nil {
+		fmt.Fprintf(stderr, "載入通知歷史失敗: %v\n", err)
+		return ExitError
+	}
+
+	client := newClient(cfg, nil)
+	for _, id := range fs.Args() {
+		now := time.Now()
+		notif := api.Notification{ID: id}
+		if e, ok := deliveries.Get(id); ok {
+			notif = e.Notification
+		}
+
+		// 先更新本機記錄，避免之後啟動的監控在伺服器狀態改變後立即重新顯示
+		if *showAgain {
+			err = deliveries.Forget(id, now)
+		} else {
+			err = deliveries.MarkReverted(notif, now)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "儲存投遞記錄失敗: %v\n", err)
+		}
+		if err := store.MarkReverted(id, now); err != nil {
+			fmt.Fprintf(stderr, "儲存通知歷史失敗: %v\n", err)
+		}
+
+		if err := client.SetStatus(context.Background(), id, api.StatusUnnotified); err != nil {
+			fmt.Fprintf(stderr, "#%s 標為未讀失敗: %v\n", id, err)
+			if code == ExitOK {
+				code = exitCode(err)
+			}
+			continue
+		}
+		fmt.Fprintf(stdout, "#%s 已標為未讀\n", id)
+	}
+	return code
+}
+
+// runAck 確認通知並更新伺服器狀態為已通知，用於 on_click 與 manual 確認模式。
+// 有執行中的程式時交給它處理
+func runAck(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("ack", stderr)
+	configPath := fs.String("config", "config.json", "設定檔路徑")
+	profile := fs.String("profile", config.DefaultProfile, "通知所屬的設定檔")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "用法: "+ackUsage)
+		return ExitUsage
+	}
+
+	cfg, ok := loadProfile(*configPath, *profile, stderr)
+	if !ok {
+		return ExitError
+	}
+	release, code, handled := handOff(*configPath, instance.AckArgs(profileIDs(*profile, fs.Args())), stdout, stderr)
+	if handled {
+		return code
+	}
+	defer release()
+
+	deliveries, err := ledger.Open(cfg.DataPath("ledger.json"))
+	if err != nil {
+		fmt.Fprintf(stderr, "載入投遞記錄失敗: %v\n", err)
+		return ExitError
+	}
+	store, err := history.Open(cfg.DataPath("history.json"), cfg.History)
+	if err != nil {
+		fmt.Fprintf(stderr, "載入通知歷史失敗: %v\n", err)
+		return ExitError
+	}
+
+	client := newClient(cfg, nil)
+	for _, id := range fs.Args() {
+		if err := client.SetStatus(context.Background(), id, api.StatusNotified); err != nil {
+			fmt.Fprintf(stderr, "#%s 確認失敗: %v\n", id, err)
+			if code == ExitOK {
+				code = exitCode(err)
+			}
+			continue
+		}
+
+		now := time.Now()
+		notif := api.Notification{ID: id}
+		if e, ok := deliveries.Get(id); ok {
+			notif = e.Notification
+			if err := deliveries.MarkUserAcked(id, now); err != nil {
+				fmt.Fprintf(stderr, "儲存投遞記錄失敗: %v\n", err)
+			}
+		}
+		if err := deliveries.MarkAcked(notif, now); err != nil {
+			fmt.Fprintf(stderr, "儲存投遞記錄失敗: %v\n", err)
+		}
+		for _, mark := range []func(string, time.Time) error{store.MarkAcked, store.MarkUserAcked, store.MarkRead} {
+			if err := mark(id, now); err != nil {
+				fmt.Fprintf(stderr, "儲存通知歷史失敗: %v\n", err)
+				break
+			}
+		}
+		fmt.Fprintf(stdout, "#%s 已確認\n", id)
+	}
+	return code
+}
@@ -0,0 +1,6 @@
+//go:build !windows
+
+package cli
+
+// attachConsole 在非 Windows 平台不需要處理
+func attachConsole() {}
@@ -0,0 +1,28 @@
+//go:build windows
+
+package cli
+
+import (
+	"os"
+	"syscall"
+)
+
+// attachParentProcess 是 AttachConsole 的 ATTACH_PARENT_PROCESS (DWORD -1)
+const attachParentProcess = ^uint32(0)
+
+// attachConsole 讓以 windowsgui 編譯的執行檔在命令提示字元中執行時輸出到上層主控台；
+// 輸出已被重新導向時維持不變
+func attachConsole() {
+	if _, err := os.Stdout.Stat(); err == nil {
+		return
+	}
+
+	proc := syscall.NewLazyDLL("kernel32.dll").NewProc("AttachConsole")
+	if ok, _, _ := proc.Call(uintptr(attachParentProcess)); ok == 0 {
+		return
+	}
+	if f, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0); err == nil {
+		os.Stdout = f
+		os.Stderr = f
+	}
+}
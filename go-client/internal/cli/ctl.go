@@ -0,0 +1,267 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"windows-notification/internal/control"
+)
+
+const ctlUsage = "ctl [-config 檔案] [-json] status|health|start|stop|pause <分鐘|tomorrow>|resume|history [-unread] [-limit N] [查詢]|ack <ID>...|config [key=value]..."
+
+// ctlExitCode 依控制 API 錯誤返回結束代碼
+func ctlExitCode(err error) int {
+	var ce *control.Error
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, control.ErrNotRunning):
+		return ExitUnavailable
+	case errors.As(err, &ce):
+		switch ce.StatusCode {
+		case http.StatusUnauthorized:
+			return ExitAuth
+		case http.StatusBadRequest:
+			return ExitUsage
+		case http.StatusServiceUnavailable:
+			return ExitUnavailable
+		}
+	}
+	return ExitError
+}
+
+// runCtl 透過本機控制 API 操作執行中的程式
+func runCtl(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("ctl", stderr)
+	configPath := fs.String("config", "config.json", "設定檔路徑")
+	asJSON := fs.Bool("json", false, "以 JSON 輸出")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "用法: "+ctlUsage)
+		return ExitUsage
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "載入設定失敗: %v\n", err)
+		return ExitError
+	}
+	client, err := control.Dial(control.StatePath(cfg))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ctlExitCode(err)
+	}
+
+	action, rest := fs.Arg(0), fs.Args()[1:]
+	var out interface{}
+	switch action {
+	case "health":
+		out, err = map[string]bool{"ok": true}, client.Health()
+	case "status":
+		out, err = client.Status()
+	case "start":
+		out, err = client.Start()
+	case "stop":
+		out, err = client.Stop()
+	case "resume":
+		out, err = client.Resume()
+	case "pause":
+		minutes, ok := parsePause(rest)
+		if !ok {
+			fmt.Fprintln(stderr, "用法: ctl pause <分鐘|tomorrow>")
+			return ExitUsage
+		}
+		var until time.Time
+		until, err = client.Pause(minutes)
+		out = map[string]time.Time{"paused_until": until}
+	case "history":
+		return ctlHistory(client, rest, *asJSON, stdout, stderr)
+	case "ack":
+		return ctlAck(client, rest, *asJSON, stdout, stderr)
+	case "config":
+		if len(rest) == 0 {
+			out, err = client.Config()
+			break
+		}
+		u, perr := parseConfigUpdate(rest)
+		if perr != nil {
+			fmt.Fprintln(stderr, perr)
+			return ExitUsage
+		}
+		out, err = client.UpdateConfig(u)
+	default:
+		fmt.Fprintf(stderr, "未知的動作: %s\n用法: %s\n", action, ctlUsage)
+		return ExitUsage
+	}
+
+	if err != nil {
+		fmt.Fprintf(stderr, "%s 失敗: %v\n", action, err)
+		return ctlExitCode(err)
+	}
+	if status, ok := out.(control.Status); ok && !*asJSON {
+		printStatus(stdout, status)
+		return ExitOK
+	}
+	if action == "health" && !*asJSON {
+		fmt.Fprintln(stdout, "ok")
+		return ExitOK
+	}
+	return printJSON(stdout, stderr, out)
+}
+
+// parsePause 解析 pause 的參數，tomorrow 以 0 分鐘表示
+func parsePause(args []string) (int, bool) {
+	if len(args) != 1 {
+		return 0, false
+	}
+	if args[0] == "tomorrow" {
+		return 0, true
+	}
+	minutes, err := strconv.Atoi(args[0])
+	return minutes, err == nil && minutes > 0
+}
+
+// parseConfigUpdate 解析 key=value 形式的設定修改
+func parseConfigUpdate(args []string) (control.ConfigUpdate, error) {
+	var u control.ConfigUpdate
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return u, fmt.Errorf("設定格式應為 key=value: %q", arg)
+		}
+		switch key {
+		case "domain":
+			u.Domain = &value
+		case "project":
+			u.Project = &value
+		case "interval":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return u, fmt.Errorf("interval 必須是正整數: %q", value)
+			}
+			u.Interval = &n
+		case "debug":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return u, fmt.Errorf("debug 必須是 true 或 false: %q", value)
+			}
+			u.Debug = &b
+		default:
+			return u, fmt.Errorf("不支援修改的設定: %s（可修改 domain、project、interval、debug）", key)
+		}
+	}
+	return u, nil
+}
+
+// ctlHistory 查詢執行中程式的通知歷史
+func ctlHistory(client *control.Client, args []string, asJSON bool, stdout, stderr io.Writer) int {
+	fs := newFlagSet("ctl history", stderr)
+	unread := fs.Bool("unread", false, "只顯示未讀")
+	limit := fs.Int("limit", 20, "最多筆數，0 使用預設值")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if *limit < 0 {
+		fmt.Fprintln(stderr, "用法: ctl history [-unread] [-limit N] [查詢]")
+		return ExitUsage
+	}
+
+	records, err := client.History(strings.Join(fs.Args(), " "), *unread, *limit)
+	if err != nil {
+		fmt.Fprintf(stderr, "history 失敗: %v\n", err)
+		return ctlExitCode(err)
+	}
+	if asJSON {
+		return printJSON(stdout, stderr, records)
+	}
+	if len(records) == 0 {
+		fmt.Fprintln(stdout, "沒有符合的通知")
+		return ExitOK
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tRECEIVED\tOUTCOME\tREAD\tPROJECT\tTITLE")
+	for _, r := range records {
+		read := "yes"
+		if r.ReadAt.IsZero() {
+			read = "no"
+		}
+		n := r.Notification
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", n.ID, r.ReceivedAt.Format("2006-01-02 15:04"), r.Outcome, read, n.Project, truncate(n.Title, titleWidth))
+	}
+	tw.Flush()
+	return ExitOK
+}
+
+// ctlAck 透過執行中的程式確認通知，任一則失敗時返回 ExitError
+func ctlAck(client *control.Client, ids []string, asJSON bool, stdout, stderr io.Writer) int {
+	if len(ids) == 0 {
+		fmt.Fprintln(stderr, "用法: ctl ack <ID>...")
+		return ExitUsage
+	}
+
+	results, err := client.Ack(ids...)
+	if err != nil {
+		fmt.Fprintf(stderr, "ack 失敗: %v\n", err)
+		return ctlExitCode(err)
+	}
+	if asJSON {
+		printJSON(stdout, stderr, results)
+	}
+
+	code := ExitOK
+	for _, r := range results {
+		if !r.OK {
+			fmt.Fprintf(stderr, "#%s 確認失敗: %s\n", r.ID, r.Error)
+			code = ExitError
+		} else if !asJSON {
+			fmt.Fprintf(stdout, "#%s 已確認\n", r.ID)
+		}
+	}
+	return code
+}
+
+// printStatus 輸出執行中程式的狀態
+func printStatus(w io.Writer, s control.Status) {
+	monitoring := "stopped"
+	if s.Monitoring {
+		monitoring = "running"
+	}
+	fmt.Fprintf(w, "Monitoring:  %s (project %q, every %ds)\n", monitoring, s.Project, s.Interval)
+	fmt.Fprintf(w, "Health:      %s\n", s.Health)
+	if !s.LastCheck.IsZero() {
+		fmt.Fprintf(w, "Last check:  %s\n", s.LastCheck.Format("2006-01-02 15:04:05"))
+	}
+	if s.LastError != "" {
+		fmt.Fprintf(w, "Last error:  %s\n", s.LastError)
+	}
+	dnd := "off"
+	if !s.PausedUntil.IsZero() {
+		dnd = "paused until " + s.PausedUntil.Format("01-02 15:04")
+	} else if s.Quiet {
+		dnd = "quiet hours"
+	}
+	fmt.Fprintf(w, "DND:         %s\n", dnd)
+	fmt.Fprintf(w, "Unread:      %d\n", s.Unread)
+	fmt.Fprintf(w, "Queued:      %d\n", s.Queued)
+	fmt.Fprintf(w, "Outbox:      %d\n", s.Outbox)
+
+	if len(s.Profiles) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROFILE\tENABLED\tMONITORING\tHEALTH\tFAILURES\tLAST ERROR")
+	for _, p := range s.Profiles {
+		fmt.Fprintf(tw, "%s\t%t\t%t\t%s\t%d\t%s\n", p.Name, p.Enabled, p.Monitoring, p.Health, p.Failures, p.LastError)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,386 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"windows-notification/internal/api"
+)
+
+const (
+	sendUsage = "send [-config 檔案] [-project 名稱] -title 標題 [-message 內容|-] [-priority P] [-type T] [-repo R] [-branch B] [-url 網址] [-meta k=v]... [-json]"
+	listUsage = "list [-config 檔案] [-project 名稱] [-status all|0|1] [-limit N] [-json]"
+	getUsage  = "get [-config 檔案] [-json] <ID>"
+	tailUsage = "tail [-config 檔案] [-project 名稱] [-status all|0|1] [-n N] [-f] [-interval 秒] [-json]"
+)
+
+// tailWindow 是 tail -f 每次查詢的筆數，兩次查詢之間新增超過此數量時較舊的會被略過
+const tailWindow = 100
+
+// titleWidth 是表格中標題欄的最大字數
+const titleWidth = 60
+
+// metaFlag 收集可重複的 -meta key=value 參數
+type metaFlag map[string]interface{}
+
+func (m metaFlag) String() string {
+	return ""
+}
+
+func (m metaFlag) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("格式應為 key=value")
+	}
+	m[k] = v
+	return nil
+}
+
+// parseStatus 解析 -status 參數
+func parseStatus(s string) (int, error) {
+	switch strings.ToLower(s) {
+	case "", "all":
+		return api.StatusAny, nil
+	case "0", "unnotified":
+		return api.StatusUnnotified, nil
+	case "1", "notified":
+		return api.StatusNotified, nil
+	default:
+		return 0, fmt.Errorf("無效的狀態 %q，應為 all、0 或 1", s)
+	}
+}
+
+// runSend 建立一則通知，成功時輸出通知 ID
+func runSend(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("send", stderr)
+	configPath := fs.String("config", "config.json", "設定檔路徑")
+	project := fs.String("project", "", "專案名稱，預設為設定檔中的專案")
+	title := fs.String("title", "", "通知標題")
+	message := fs.String("message", "", "通知內容，- 代表從標準輸入讀取")
+	priority := fs.String("priority", "", "優先權")
+	typ := fs.String("type", "", "通知類型")
+	repo := fs.String("repo", "", "倉庫")
+	branch := fs.String("branch", "", "分支")
+	actionURL := fs.String("url", "", "點擊通知時開啟的網址")
+	meta := metaFlag{}
+	fs.Var(meta, "meta", "附加的 metadata，格式 key=value，可重複")
+	asJSON := fs.Bool("json", false, "以 JSON 輸出建立的通知")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if fs.NArg() > 0 || *title == "" {
+		fmt.Fprintln(stderr, "用法: "+sendUsage)
+		return ExitUsage
+	}
+
+	if *message == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "讀取標準輸入失敗: %v\n", err)
+			return ExitError
+		}
+		*message = strings.TrimRight(string(data), "\r\n")
+	}
+	if *message == "" {
+		fmt.Fprintln(stderr, "需要 -message 或以 -message - 從標準輸入讀取內容")
+		return ExitUsage
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "載入設定失敗: %v\n", err)
+		return ExitError
+	}
+	if *project == "" {
+		*project = cfg.Project
+	}
+	if *project == "" {
+		fmt.Fprintln(stderr, "需要 -project（設定檔中也未指定專案）")
+		return ExitUsage
+	}
+
+	req := api.CreateRequest{
+		Project:   *project,
+		Title:     *title,
+		Message:   *message,
+		Type:      *typ,
+		Priority:  *priority,
+		Repo:      *repo,
+		Branch:    *branch,
+		ActionURL: *actionURL,
+	}
+	if len(meta) > 0 {
+		req.Metadata = meta
+	}
+
+	n, err := newClient(cfg, nil).CreateNotification(req)
+	if err != nil {
+		fmt.Fprintf(stderr, "建立通知失敗: %v\n", err)
+		return exitCode(err)
+	}
+	if *asJSON {
+		return printJSON(stdout, stderr, n)
+	}
+	fmt.Fprintln(stdout, n.ID)
+	return ExitOK
+}
+
+// runList 依條件查詢通知並以表格或 JSON 輸出
+func runList(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("list", stderr)
+	configPath := fs.String("config", "config.json", "設定檔路徑")
+	project := fs.String("project", "", "專案名稱，預設為設定檔中的專案")
+	status := fs.String("status", "all", "狀態：all、0（未通知）或 1（已通知）")
+	limit := fs.Int("limit", 50, "最多筆數，0 使用伺服器預設值")
+	asJSON := fs.Bool("json", false, "以 JSON 陣列輸出")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	st, err := parseStatus(*status)
+	if err != nil || fs.NArg() > 0 || *limit < 0 {
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+		}
+		fmt.Fprintln(stderr, "用法: "+listUsage)
+		return ExitUsage
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "載入設定失敗: %v\n", err)
+		return ExitError
+	}
+	if *project == "" {
+		*project = cfg.Project
+	}
+
+	items, err := newClient(cfg, nil).ListNotifications(context.Background(), api.ListOptions{Project: *project, Status: st, Limit: *limit})
+	if err != nil {
+		fmt.Fprintf(stderr, "查詢失敗: %v\n", err)
+		return exitCode(err)
+	}
+	if *asJSON {
+		if items == nil {
+			items = []api.Notification{}
+		}
+		return printJSON(stdout, stderr, items)
+	}
+	printTable(stdout, items)
+	return ExitOK
+}
+
+// runGet 顯示單一通知的完整內容
+func runGet(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("get", stderr)
+	configPath := fs.String("config", "config.json", "設定檔路徑")
+	asJSON := fs.Bool("json", false, "以 JSON 輸出")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "用法: "+getUsage)
+		return ExitUsage
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "載入設定失敗: %v\n", err)
+		return ExitError
+	}
+
+	n, err := newClient(cfg, nil).GetNotification(context.Background(), fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "#%s 查詢失敗: %v\n", fs.Arg(0), err)
+		return exitCode(err)
+	}
+	if *asJSON {
+		return printJSON(stdout, stderr, n)
+	}
+	printDetail(stdout, *n)
+	return ExitOK
+}
+
+// runTail 顯示最近的通知，-f 時持續輸出新通知直到收到 SIGINT/SIGTERM
+func runTail(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("tail", stderr)
+	configPath := fs.String("config", "config.json", "設定檔路徑")
+	project := fs.String("project", "", "專案名稱，預設為設定檔中的專案")
+	status := fs.String("status", "all", "狀態：all、0（未通知）或 1（已通知）")
+	count := fs.Int("n", 10, "先顯示最近的筆數")
+	follow := fs.Bool("f", false, "持續輸出新通知")
+	interval := fs.Int("interval", 0, "查詢間隔秒數，預設為設定檔中的間隔")
+	asJSON := fs.Bool("json", false, "每行輸出一則 JSON")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	st, err := parseStatus(*status)
+	if err != nil || fs.NArg() > 0 || *count < 0 || *interval < 0 {
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+		}
+		fmt.Fprintln(stderr, "用法: "+tailUsage)
+		return ExitUsage
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "載入設定失敗: %v\n", err)
+		return ExitError
+	}
+	if *project == "" {
+		*project = cfg.Project
+	}
+	if *interval == 0 {
+		*interval = cfg.Interval
+	}
+	if *interval <= 0 {
+		*interval = 5
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := newClient(cfg, nil)
+	opts := api.ListOptions{Project: *project, Status: st, Limit: tailWindow}
+	items, err := client.ListNotifications(ctx, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "查詢失敗: %v\n", err)
+		return exitCode(err)
+	}
+
+	// 伺服器由新到舊排序，輸出時改為由舊到新
+	seen := make(map[string]bool, len(items))
+	for _, n := range items {
+		seen[n.ID] = true
+	}
+	recent := items
+	if len(recent) > *count {
+		recent = recent[:*count]
+	}
+	emit := func(n api.Notification) {
+		if *asJSON {
+			data, _ := json.Marshal(n)
+			fmt.Fprintln(stdout, string(data))
+			return
+		}
+		fmt.Fprintf(stdout, "%s  #%s  [%s] %s%s\n", n.CreatedAt, n.ID, n.Project, priorityTag(n), n.Title)
+	}
+	for i := len(recent) - 1; i >= 0; i-- {
+		emit(recent[i])
+	}
+	if !*follow {
+		return ExitOK
+	}
+
+	ticker := time.NewTicker(time.Duration(*interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ExitOK
+		case <-ticker.C:
+		}
+
+		items, err := client.ListNotifications(ctx, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ExitOK
+			}
+			fmt.Fprintf(stderr, "查詢失敗，稍後重試: %v\n", err)
+			continue
+		}
+
+		// 只保留目前查詢範圍內的 ID，避免長時間執行時無限增長
+		current := make(map[string]bool, len(items))
+		for i := len(items) - 1; i >= 0; i-- {
+			n := items[i]
+			current[n.ID] = true
+			if !seen[n.ID] {
+				emit(n)
+			}
+		}
+		seen = current
+	}
+}
+
+// printJSON 以縮排 JSON 輸出
+func printJSON(stdout, stderr io.Writer, v interface{}) int {
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(stderr, "輸出 JSON 失敗: %v\n", err)
+		return ExitError
+	}
+	return ExitOK
+}
+
+// printTable 以表格輸出通知列表
+func printTable(w io.Writer, items []api.Notification) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tCREATED\tSTATUS\tPROJECT\tPRIORITY\tTITLE")
+	for _, n := range items {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", n.ID, n.CreatedAt, n.Status, n.Project, n.Priority, truncate(n.Title, titleWidth))
+	}
+	tw.Flush()
+}
+
+// printDetail 輸出單一通知的所有欄位
+func printDetail(w io.Writer, n api.Notification) {
+	fields := []struct{ name, value string }{
+		{"ID", n.ID},
+		{"Project", n.Project},
+		{"Title", n.Title},
+		{"Status", n.Status},
+		{"Priority", n.Priority},
+		{"Type", n.Type},
+		{"Repo", n.Repo},
+		{"Branch", n.Branch},
+		{"URL", n.ActionURL},
+		{"Created", n.CreatedAt},
+		{"Notified", n.NotifiedAt},
+	}
+	for _, f := range fields {
+		if f.value != "" {
+			fmt.Fprintf(w, "%-9s %s\n", f.name+":", f.value)
+		}
+	}
+	if len(n.Metadata) > 0 {
+		keys := make([]string, 0, len(n.Metadata))
+		for k := range n.Metadata {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fmt.Fprintln(w, "Metadata:")
+		for _, k := range keys {
+			fmt.Fprintf(w, "  %s: %v\n", k, n.Metadata[k])
+		}
+	}
+	fmt.Fprintf(w, "\n%s\n", n.Message)
+}
+
+// priorityTag 返回優先權標記，沒有優先權時為空字串
+func priorityTag(n api.Notification) string {
+	if n.Priority == "" {
+		return ""
+	}
+	return "(" + n.Priority + ") "
+}
+
+// truncate 將文字截斷為最多 max 個字，換行改為空白
+func truncate(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max-1]) + "…"
+}
@@ -0,0 +1,266 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"windows-notification/internal/actions"
+	"windows-notification/internal/config"
+	"windows-notification/internal/instance"
+	"windows-notification/internal/logger"
+	"windows-notification/internal/monitor"
+	"windows-notification/internal/notification"
+)
+
+const runUsage = "run [-headless] [-config 檔案] [-project 名稱] [-interval 秒] [-notifier 名稱] [-once]"
+
+// runMonitor 不開啟視窗執行監控，直到收到 SIGINT/SIGTERM；
+// -headless 用於沒有桌面工作階段的環境，通知改由主控台或指定的後端輸出
+func runMonitor(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("run", stderr)
+	configPath := fs.String("config", "config.json", "設定檔路徑")
+	headless := fs.Bool("headless", false, "不使用 Windows 系統通知，預設輸出到主控台")
+	project := fs.String("project", "", "監控的專案，覆蓋設定檔")
+	interval := fs.Int("interval", 0, "查詢間隔秒數，覆蓋設定檔")
+	notifier := fs.String("notifier", "", "無視窗模式的預設通知後端（notifiers 中的名稱）")
+	once := fs.Bool("once", false, "只查詢一次後結束")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintln(stderr, "用法: "+runUsage)
+		return ExitUsage
+	}
+	if *interval != 0 {
+		if err := config.ValidateInterval(*interval); err != nil {
+			fmt.Fprintf(stderr, "-interval %v\n", err)
+			return ExitUsage
+		}
+	}
+	if *notifier != "" && !*headless {
+		fmt.Fprintln(stderr, "-notifier 只能與 -headless 一起使用")
+		return ExitUsage
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "載入設定失敗: %v\n", err)
+		return ExitError
+	}
+
+	// 相同設定檔同時只能有一個監控，避免重複查詢同一批未通知記錄而重複顯示
+	lock, err := instance.Acquire(instance.Path(*configPath))
+	if err != nil {
+		var running *instance.RunningError
+		if errors.As(err, &running) {
+			fmt.Fprintln(stderr, err)
+			return ExitError
+		}
+		fmt.Fprintf(stderr, "警告: %v，未啟用單一執行個體檢查\n", err)
+	} else {
+		defer lock.Release()
+	}
+
+	if *project != "" {
+		cfg.Project = *project
+	}
+	if *interval > 0 {
+		cfg.Interval = *interval
+	}
+
+	// 日誌同時寫入檔案與標準輸出
+	log, err := logger.New(cfg.Debug)
+	if err != nil {
+		fmt.Fprintf(stderr, "警告: 無法創建 logger: %v\n", err)
+	} else {
+		log.SetConsole(stdout)
+		defer log.Close()
+	}
+
+	// -notifier 優先於設定檔的預設通知後端
+	defaultNotifier := cfg.Notifier
+	if *notifier != "" {
+		defaultNotifier = *notifier
+	}
+	backends, err := newBackends(cfg.Notifiers, *headless, defaultNotifier, log)
+	if err != nil {
+		fmt.Fprintf(stderr, "通知後端設定無效: %v\n", err)
+		return ExitError
+	}
+
+	// 有桌面工作階段時才提供通知動作按鈕；非 default 設定檔的通知 ID 以 "名稱/" 區分。
+	// 引擎需要動作服務的網址，因此服務先啟動，建立引擎期間收到的動作以 enginesMu 保護
+	engines := make(engineCommands)
+	var enginesMu sync.Mutex
+	var server *actions.Server
+	if !*headless {
+		server, err = actions.Start(func(action, id, arg string) error {
+			enginesMu.Lock()
+			e, id, err := engines.action(id)
+			enginesMu.Unlock()
+			if err != nil {
+				return err
+			}
+			return e.HandleAction(action, id, arg)
+		}, log)
+		if err != nil {
+			if log != nil {
+				log.Errorf("%v，通知將不顯示動作按鈕", err)
+			}
+			server = nil
+		} else {
+			defer server.Close()
+		}
+	}
+
+	// 每個啟用的設定檔各自一個引擎；只有一個設定檔時通知標題不加上名稱
+	all := cfg.AllProfiles()
+	var list []*monitor.Engine
+	for _, p := range all {
+		if p.Disabled {
+			continue
+		}
+		pcfg, err := cfg.ForProfile(p.Name)
+		if err != nil {
+			fmt.Fprintf(stderr, "設定檔 %s 無效: %v\n", p.Name, err)
+			return ExitError
+		}
+		opts := monitor.Options{
+			Config:   pcfg,
+			Source:   newClient(pcfg, log),
+			Backends: backends,
+			Logger:   log,
+		}
+		if len(all) > 1 {
+			opts.Profile = p.Name
+		}
+		if server != nil {
+			opts.Actions = server
+			if p.Name != config.DefaultProfile {
+				opts.Actions = profileActions{server: server, prefix: p.Name + "/"}
+			}
+		}
+		monitor.OpenStores(pcfg, log).Apply(&opts)
+
+		engine := monitor.New(opts)
+		defer engine.Close()
+		enginesMu.Lock()
+		engines[p.Name] = engine
+		enginesMu.Unlock()
+		list = append(list, engine)
+	}
+	if len(list) == 0 {
+		fmt.Fprintln(stderr, "沒有啟用的設定檔")
+		return ExitError
+	}
+
+	// 引擎建立後才接收轉交的命令；命令列的 ack 與 unread 由引擎更新記錄，避免兩個程式同時寫入
+	if lock != nil {
+		lock.Handle(func(args []string) (string, error) {
+			if len(args) == 0 || args[0] == instance.Show {
+				return "", fmt.Errorf("執行中的程式以 run 子命令執行，沒有視窗可顯示")
+			}
+			return instance.Dispatch(engines, args)
+		})
+	}
+
+	if *once {
+		code := ExitOK
+		for _, engine := range list {
+			engine.Check()
+			if engine.Status().Health == monitor.HealthDown {
+				code = ExitError
+			}
+		}
+		return code
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for _, engine := range list {
+		if err := engine.Start(); err != nil {
+			fmt.Fprintf(stderr, "啟動監控失敗: %v\n", err)
+			return ExitError
+		}
+	}
+
+	<-ctx.Done()
+	if log != nil {
+		log.Info("收到結束信號，正在停止監控...")
+	}
+	return ExitOK
+}
+
+// profileActions 在通知 ID 前加上設定檔名稱，讓動作按鈕轉交給正確的引擎
+type profileActions struct {
+	server *actions.Server
+	prefix string
+}
+
+// URL 返回觸發動作的網址
+func (a profileActions) URL(action, id, arg string) string {
+	return a.server.URL(action, a.prefix+id, arg)
+}
+
+// engineCommands 將轉交的命令交給通知所屬設定檔的引擎
+type engineCommands map[string]*monitor.Engine
+
+// engine 拆出 "名稱/ID" 形式中的設定檔；未指定設定檔時為 default
+func (c engineCommands) engine(id string) (*monitor.Engine, string, error) {
+	name, rest, ok := strings.Cut(id, "/")
+	if !ok {
+		name, rest = config.DefaultProfile, id
+	}
+	if e := c[name]; e != nil {
+		return e, rest, nil
+	}
+	return nil, "", fmt.Errorf("設定檔 %s 未啟用", name)
+}
+
+// action 依動作網址中的通知 ID 找到引擎；沒有符合的設定檔時視為 default 的通知
+func (c engineCommands) action(id string) (*monitor.Engine, string, error) {
+	if name, rest, ok := strings.Cut(id, "/"); ok && c[name] != nil {
+		return c[name], rest, nil
+	}
+	if e := c[config.DefaultProfile]; e != nil {
+		return e, id, nil
+	}
+	return nil, "", fmt.Errorf("找不到通知 %s 所屬的設定檔", id)
+}
+
+func (c engineCommands) AckNow(id string) error {
+	e, id, err := c.engine(id)
+	if err != nil {
+		return err
+	}
+	return e.AckNow(id)
+}
+
+func (c engineCommands) MarkUnread(id string, showAgain bool) error {
+	e, id, err := c.engine(id)
+	if err != nil {
+		return err
+	}
+	return e.MarkUnreadByID(id, showAgain)
+}
+
+// newBackends 建立通知後端並以 name 作為預設後端；無視窗模式以主控台取代 Windows 系統通知
+func newBackends(cfgs map[string]config.NotifierConfig, headless bool, name string, log *logger.Logger) (*notification.Registry, error) {
+	var base notification.Backend = notification.Console{}
+	if !headless {
+		base = notification.NewNotifier("Windows Notification Monitor", log)
+	}
+	registry, err := notification.NewRegistry(base, cfgs, log)
+	if err != nil {
+		return nil, err
+	}
+	return registry.WithDefault(name)
+}
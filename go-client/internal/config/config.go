@@ -2,18 +2,176 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
+
+	"windows-notification/internal/jsonfile"
+	"windows-notification/internal/render"
+)
+
+// DefaultDataDir 是本機狀態檔的預設目錄
+const DefaultDataDir = "data"
+
+// 確認模式，決定何時將伺服器狀態更新為已通知
+const (
+	AckOnDisplay = "on_display" // 顯示後立即更新（預設）
+	AckOnClick   = "on_click"   // 點擊通知或按下確認後才更新
+	AckManual    = "manual"     // 只有按下確認（通知、收件匣或命令列）後才更新
 )
 
 // Config 代表應用程式的設定
 type Config struct {
 	Domain   string `json:"domain"`   // API 網域
+	APIKey   string `json:"api_key"`  // API 金鑰，以 Authorization: Bearer 送出，留空不送
 	Project  string `json:"project"`  // 專案名稱篩選
 	Interval int    `json:"interval"` // 查詢間隔（秒）
 	Debug    bool   `json:"debug"`    // Debug 模式
+	DataDir  string `json:"data_dir"` // 本機狀態檔目錄
+	AckMode  string `json:"ack_mode"` // 確認模式：on_display、on_click、manual
+	Disabled bool   `json:"disabled"` // 停用頂層（default）設定檔，只監控 profiles
+
+	Profiles []Profile `json:"profiles"` // 其他伺服器設定檔，各自以獨立的引擎同時監控
+
+	QuietHours QuietHours                `json:"quiet_hours"` // 勿擾時段
+	Rules      []Rule                    `json:"rules"`       // 通知規則（依序比對）
+	Notifier   string                    `json:"notifier"`    // 預設通知後端，為 notifiers 中的名稱，留空為 Windows 系統通知
+	Notifiers  map[string]NotifierConfig `json:"notifiers"`   // 額外的通知後端
+	Dedup      Dedup                     `json:"dedup"`       // 重複通知合併
+	RateLimit  RateLimit                 `json:"rate_limit"`  // 通知顯示限流
+	Templates  map[string]render.Spec    `json:"templates"`   // 依專案的標題與內容樣板，* 為預設
+	Quarantine Quarantine                `json:"quarantine"`  // 顯示失敗隔離
+	Escalation []Escalation              `json:"escalation"`  // 未確認通知的升級政策（依序比對）
+	History    History                   `json:"history"`     // 本機通知歷史保留設定
+	Control    Control                   `json:"control"`     // 本機控制 API
+	Tray       Tray                      `json:"tray"`        // 系統匣
+}
+
+// DefaultProfile 是頂層 domain、api_key、project、interval 對應的設定檔名稱
+const DefaultProfile = "default"
+
+// Profile 代表一個要監控的伺服器；規則、勿擾、通知後端等其他設定與頂層共用
+type Profile struct {
+	Name     string `json:"name"`     // 名稱，顯示在通知與視窗中
+	Domain   string `json:"domain"`   // API 網域
+	APIKey   string `json:"api_key"`  // API 金鑰，留空不送
+	Project  string `json:"project"`  // 專案名稱篩選
+	Interval int    `json:"interval"` // 查詢間隔（秒），0 沿用頂層設定
+	Disabled bool   `json:"disabled"` // 停用時不監控
+}
+
+// Tray 代表系統匣設定
+type Tray struct {
+	CloseToTray    bool `json:"close_to_tray"`   // 關閉視窗時隱藏到系統匣，不結束程式
+	StartMinimized bool `json:"start_minimized"` // 啟動時不顯示視窗，只顯示系統匣圖示
+}
+
+// Control 代表本機控制 API 設定，供腳本查詢與控制執行中的程式
+type Control struct {
+	Enabled bool   `json:"enabled"` // 是否啟動控制 API
+	Address string `json:"address"` // 監聽位址，只允許本機，預設 127.0.0.1:0（隨機埠）
+	Token   string `json:"token"`   // 存取權杖，留空時每次啟動隨機產生
+}
+
+// History 代表本機通知歷史的保留設定
+type History struct {
+	MaxDays    int `json:"max_days"`    // 保留天數，預設 30
+	MaxRecords int `json:"max_records"` // 最多保留筆數，預設 5000
+}
+
+// Escalation 代表未確認通知的升級政策，符合的通知需要使用者明確確認
+type Escalation struct {
+	Project       string `json:"project"`        // 專案（支援 * 萬用字元），留空代表全部
+	Priority      string `json:"priority"`       // 優先權（支援 * 萬用字元），留空代表全部
+	RenotifyAfter int    `json:"renotify_after"` // 幾分鐘未確認後以強調樣式重新顯示，0 不重新顯示
+	EscalateAfter int    `json:"escalate_after"` // 幾分鐘未確認後送到次要通道，0 不升級
+	Notifier      string `json:"notifier"`       // 次要通道，為 notifiers 中的名稱
+}
+
+// Quarantine 代表顯示失敗隔離設定
+type Quarantine struct {
+	Threshold     int    `json:"threshold"`      // 連續顯示失敗幾次後隔離，預設 3
+	Report        bool   `json:"report"`         // 隔離時是否建立一則通知回報伺服器
+	ReportProject string `json:"report_project"` // 回報通知的專案名稱，預設 notification-client
+}
+
+// RateLimit 代表通知顯示限流設定，超出上限的通知會排隊稍後顯示
+type RateLimit struct {
+	Enabled          bool `json:"enabled"`           // 是否啟用
+	Global           int  `json:"global"`            // 全部專案每分鐘最多顯示數，0 不限制
+	PerProject       int  `json:"per_project"`       // 每個專案每分鐘最多顯示數，0 不限制
+	SummaryThreshold int  `json:"summary_threshold"` // 佇列達到此數量時合併為一則摘要，0 不合併
+}
+
+// Dedup 代表重複通知合併設定
+type Dedup struct {
+	Enabled bool     `json:"enabled"` // 是否啟用
+	Key     []string `json:"key"`     // 去重欄位，預設 project、title、message
+	Window  int      `json:"window"`  // 時間窗（秒），預設 60
+}
+
+// QuietHours 代表勿擾時段設定
+type QuietHours struct {
+	Enabled      bool            `json:"enabled"`                 // 是否啟用排程勿擾
+	Timezone     string          `json:"timezone"`                // IANA 時區，留空使用本機時區
+	Schedules    []QuietSchedule `json:"schedules"`               // 每週勿擾時段
+	Breakthrough []string        `json:"breakthrough_priorities"` // 可穿透勿擾的優先權，留空為 critical
+}
+
+// QuietSchedule 代表單一每週勿擾時段
+type QuietSchedule struct {
+	Days  []string `json:"days"`  // 星期（mon、tue...），留空代表每天
+	Start string   `json:"start"` // 開始時間 HH:MM
+	End   string   `json:"end"`   // 結束時間 HH:MM，早於開始時間表示跨日
 }
 
-// Load 從指定路徑載入設定檔
+// Rule 代表一條通知規則
+type Rule struct {
+	Name   string     `json:"name"`   // 規則名稱
+	Match  RuleMatch  `json:"match"`  // 比對條件，全部符合才算命中
+	Action RuleAction `json:"action"` // 命中後的動作
+}
+
+// RuleMatch 代表規則的比對條件，留空的欄位不參與比對
+type RuleMatch struct {
+	Project  string            `json:"project"`  // 專案（支援 * 萬用字元）
+	Title    string            `json:"title"`    // 標題正規表示式
+	Message  string            `json:"message"`  // 內容正規表示式
+	Priority string            `json:"priority"` // 優先權（支援 * 萬用字元）
+	Type     string            `json:"type"`     // 類型（支援 * 萬用字元）
+	Repo     string            `json:"repo"`     // 儲存庫（支援 * 萬用字元）
+	Branch   string            `json:"branch"`   // 分支（支援 * 萬用字元）
+	Metadata map[string]string `json:"metadata"` // metadata 鍵對應值的正規表示式，空字串代表只要求鍵存在
+}
+
+// RuleAction 代表規則命中後的動作
+type RuleAction struct {
+	Type     string `json:"type"`     // show、suppress、ack、priority、rewrite_title、route、forward
+	Priority string `json:"priority"` // priority 動作的新優先權
+	Title    string `json:"title"`    // rewrite_title 動作的新標題，{title} 代表原標題
+	Notifier string `json:"notifier"` // route 動作的通知後端名稱
+	URL      string `json:"url"`      // forward 動作的轉送網址
+}
+
+// NotifierConfig 代表一個通知後端
+type NotifierConfig struct {
+	Type    string   `json:"type"`    // toast、console、webhook、command
+	URL     string   `json:"url"`     // webhook 網址
+	Command []string `json:"command"` // command 的執行檔與參數，標題與內容會附加在最後
+}
+
+// Default 返回沒有設定檔時使用的預設設定
+func Default() *Config {
+	return &Config{
+		Domain:   "http://localhost:9204",
+		Interval: 5,
+		DataDir:  DefaultDataDir,
+		AckMode:  AckOnDisplay,
+	}
+}
+
+// Load 從指定路徑載入設定檔；欄位無效時同時返回解碼後的設定與 ValidationError，
+// 呼叫端可顯示欄位錯誤，但在修正前不應以此設定覆寫設定檔
 func Load(path string) (*Config, error) {
 	file, err := os.Open(path)
 	if err != nil {
@@ -31,19 +189,87 @@ func Load(path string) (*Config, error) {
 	if cfg.Interval == 0 {
 		cfg.Interval = 5
 	}
+	if cfg.DataDir == "" {
+		cfg.DataDir = DefaultDataDir
+	}
+	if cfg.AckMode == "" {
+		cfg.AckMode = AckOnDisplay
+	}
 
+	if err := cfg.Validate(); err != nil {
+		return &cfg, err
+	}
 	return &cfg, nil
 }
 
-// Save 將設定儲存到指定路徑
+// Save 將設定儲存到指定路徑；先寫入暫存檔再重新命名，寫入中途失敗時保留原本的設定檔
 func Save(path string, cfg *Config) error {
-	file, err := os.Create(path)
-	if err != nil {
-		return err
+	return jsonfile.Save(path, cfg)
+}
+
+// DataPath 返回本機狀態檔的完整路徑
+func (c *Config) DataPath(name string) string {
+	dir := c.DataDir
+	if dir == "" {
+		dir = DefaultDataDir
 	}
-	defer file.Close()
+	return filepath.Join(dir, name)
+}
+
+// AllProfiles 返回所有設定檔，第一個為頂層設定對應的 default
+func (c *Config) AllProfiles() []Profile {
+	all := []Profile{{
+		Name:     DefaultProfile,
+		Domain:   c.Domain,
+		APIKey:   c.APIKey,
+		Project:  c.Project,
+		Interval: c.Interval,
+		Disabled: c.Disabled,
+	}}
+	return append(all, c.Profiles...)
+}
+
+// ForProfile 返回設定檔對應的完整設定；default 返回 c 本身，其他設定檔複製頂層設定，
+// 並將本機狀態放在 data_dir/profiles/<名稱>，避免不同伺服器的通知 ID 互相衝突
+func (c *Config) ForProfile(name string) (*Config, error) {
+	if name == DefaultProfile {
+		return c, nil
+	}
+	for _, p := range c.Profiles {
+		if p.Name != name {
+			continue
+		}
+		cfg := *c
+		cfg.Domain = p.Domain
+		cfg.APIKey = p.APIKey
+		cfg.Project = p.Project
+		if p.Interval > 0 {
+			cfg.Interval = p.Interval
+		}
+		cfg.Disabled = p.Disabled
+		cfg.DataDir = filepath.Join(c.DataPath("profiles"), p.Name)
+		cfg.Profiles = nil
+		return &cfg, nil
+	}
+	return nil, fmt.Errorf("找不到設定檔: %s", name)
+}
+
+// SetProfileDisabled 啟用或停用設定檔
+func (c *Config) SetProfileDisabled(name string, disabled bool) error {
+	if name == DefaultProfile {
+		c.Disabled = disabled
+		return nil
+	}
+	for i := range c.Profiles {
+		if c.Profiles[i].Name == name {
+			c.Profiles[i].Disabled = disabled
+			return nil
+		}
+	}
+	return fmt.Errorf("找不到設定檔: %s", name)
+}
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(cfg)
+// ExplicitAck 判斷是否需要使用者確認後才更新伺服器狀態
+func (c *Config) ExplicitAck() bool {
+	return c.AckMode == AckOnClick || c.AckMode == AckManual
 }
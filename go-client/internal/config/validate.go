@@ -0,0 +1,298 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"windows-notification/internal/render"
+)
+
+// 查詢間隔的範圍（秒），與 shared/config/schema.json 的 interval 一致
+const (
+	MinInterval = 1
+	MaxInterval = 3600
+)
+
+// MaxHistoryRecords 是 history.max_records 的上限，與 shared/config/schema.json 一致；
+// 歷史全部保存在記憶體中，查詢與搜尋會逐筆掃描，筆數過多會拖慢介面
+const MaxHistoryRecords = 20000
+
+// 以下列舉值與 shared/config/schema.json 中對應的 enum 一致
+var (
+	RuleActionTypes = []string{"show", "suppress", "ack", "priority", "rewrite_title", "route", "forward"}
+	NotifierTypes   = []string{"toast", "console", "webhook", "command"}
+	DedupKeys       = []string{"project", "title", "message", "type", "priority", "repo", "branch"}
+	weekdays        = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// FieldError 代表單一設定欄位的錯誤，Field 為 JSON 欄位路徑，例如 profiles[0].domain
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError 列出設定中所有無效的欄位
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return "設定無效: " + strings.Join(msgs, "; ")
+}
+
+// Field 返回指定欄位的錯誤訊息，欄位沒有錯誤時返回空字串
+func (e ValidationError) Field(name string) string {
+	for _, fe := range e {
+		if fe.Field == name {
+			return fe.Message
+		}
+	}
+	return ""
+}
+
+// Has 判斷是否有欄位以 prefix 開頭，例如 profiles[0].
+func (e ValidationError) Has(prefix string) bool {
+	for _, fe := range e {
+		if strings.HasPrefix(fe.Field, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateDomain 檢查 API 網域：必填，且需包含 http:// 或 https:// 與主機名稱
+func ValidateDomain(domain string) error {
+	if strings.TrimSpace(domain) == "" {
+		return fmt.Errorf("必填")
+	}
+	u, err := url.Parse(domain)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("需以 http:// 或 https:// 開頭，例如 http://localhost:9204")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("缺少主機名稱")
+	}
+	return nil
+}
+
+// ValidateInterval 檢查查詢間隔是否在 MinInterval 到 MaxInterval 秒之間
+func ValidateInterval(seconds int) error {
+	if seconds < MinInterval || seconds > MaxInterval {
+		return fmt.Errorf("需介於 %d 到 %d 秒", MinInterval, MaxInterval)
+	}
+	return nil
+}
+
+// Validate 檢查設定，無效時返回列出所有欄位錯誤的 ValidationError
+func (c *Config) Validate() error {
+	var errs ValidationError
+	add := func(field string, err error) {
+		if err != nil {
+			errs = append(errs, FieldError{Field: field, Message: err.Error()})
+		}
+	}
+
+	add("domain", ValidateDomain(c.Domain))
+	add("interval", ValidateInterval(c.Interval))
+
+	switch c.AckMode {
+	case "", AckOnDisplay, AckOnClick, AckManual:
+	default:
+		add("ack_mode", fmt.Errorf("需為 %s、%s 或 %s: %q", AckOnDisplay, AckOnClick, AckManual, c.AckMode))
+	}
+
+	if c.Notifier != "" && !knownNotifier(c.Notifiers, c.Notifier) {
+		add("notifier", fmt.Errorf("不在 notifiers 中: %q", c.Notifier))
+	}
+
+	errs = append(errs, validateProfiles(c.Profiles)...)
+	errs = append(errs, validateQuietHours(c.QuietHours)...)
+	errs = append(errs, validateRules(c.Rules, c.Notifiers)...)
+	errs = append(errs, validateNotifiers(c.Notifiers)...)
+
+	for i, k := range c.Dedup.Key {
+		if !contains(DedupKeys, k) {
+			add(fmt.Sprintf("dedup.key[%d]", i), fmt.Errorf("無效的去重欄位 %q", k))
+		}
+	}
+
+	// 數量與時間設定為 0 時使用預設值或不啟用，負數一律無效
+	nonNegative := func(field string, v int) {
+		if v < 0 {
+			add(field, fmt.Errorf("不可為負數: %d", v))
+		}
+	}
+	nonNegative("dedup.window", c.Dedup.Window)
+	nonNegative("rate_limit.global", c.RateLimit.Global)
+	nonNegative("rate_limit.per_project", c.RateLimit.PerProject)
+	nonNegative("rate_limit.summary_threshold", c.RateLimit.SummaryThreshold)
+	nonNegative("quarantine.threshold", c.Quarantine.Threshold)
+	nonNegative("history.max_days", c.History.MaxDays)
+	nonNegative("history.max_records", c.History.MaxRecords)
+	if c.History.MaxRecords > MaxHistoryRecords {
+		add("history.max_records", fmt.Errorf("不可超過 %d: %d", MaxHistoryRecords, c.History.MaxRecords))
+	}
+	for i, e := range c.Escalation {
+		nonNegative(fmt.Sprintf("escalation[%d].renotify_after", i), e.RenotifyAfter)
+		nonNegative(fmt.Sprintf("escalation[%d].escalate_after", i), e.EscalateAfter)
+		switch {
+		case e.EscalateAfter > 0 && e.Notifier == "":
+			add(fmt.Sprintf("escalation[%d].notifier", i), fmt.Errorf("escalate_after 需要 notifier"))
+		case e.Notifier != "" && !knownNotifier(c.Notifiers, e.Notifier):
+			add(fmt.Sprintf("escalation[%d].notifier", i), fmt.Errorf("不在 notifiers 中: %q", e.Notifier))
+		}
+	}
+
+	// 樣板在載入時編譯，避免到顯示時才發現錯誤
+	if _, err := render.NewSet(c.Templates); err != nil {
+		add("templates", err)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateProfiles 檢查設定檔名稱、網域與查詢間隔；名稱會作為本機狀態的目錄名稱
+func validateProfiles(profiles []Profile) ValidationError {
+	var errs ValidationError
+	add := func(i int, field string, err error) {
+		errs = append(errs, FieldError{Field: fmt.Sprintf("profiles[%d].%s", i, field), Message: err.Error()})
+	}
+
+	seen := map[string]bool{DefaultProfile: true}
+	for i, p := range profiles {
+		switch {
+		case p.Name == "":
+			add(i, "name", fmt.Errorf("必填"))
+		case seen[p.Name]:
+			add(i, "name", fmt.Errorf("名稱重複或為保留名稱: %q", p.Name))
+		case strings.ContainsAny(p.Name, `/\:*?"<>|`) || p.Name == "." || p.Name == "..":
+			add(i, "name", fmt.Errorf("不可包含路徑字元: %q", p.Name))
+		}
+		seen[p.Name] = true
+
+		if err := ValidateDomain(p.Domain); err != nil {
+			add(i, "domain", err)
+		}
+		// 0 代表沿用頂層設定
+		if p.Interval != 0 {
+			if err := ValidateInterval(p.Interval); err != nil {
+				add(i, "interval", err)
+			}
+		}
+	}
+	return errs
+}
+
+// validateQuietHours 檢查時區、時段的時間格式（HH:MM）與星期
+func validateQuietHours(q QuietHours) ValidationError {
+	var errs ValidationError
+	add := func(field string, err error) {
+		errs = append(errs, FieldError{Field: field, Message: err.Error()})
+	}
+
+	if q.Timezone != "" {
+		if _, err := time.LoadLocation(q.Timezone); err != nil {
+			add("quiet_hours.timezone", fmt.Errorf("無效的時區 %q", q.Timezone))
+		}
+	}
+	for i, s := range q.Schedules {
+		prefix := fmt.Sprintf("quiet_hours.schedules[%d].", i)
+		if _, err := time.Parse("15:04", strings.TrimSpace(s.Start)); err != nil {
+			add(prefix+"start", fmt.Errorf("無效的時間 %q（格式應為 HH:MM）", s.Start))
+		}
+		if _, err := time.Parse("15:04", strings.TrimSpace(s.End)); err != nil {
+			add(prefix+"end", fmt.Errorf("無效的時間 %q（格式應為 HH:MM）", s.End))
+		}
+		for j, d := range s.Days {
+			d = strings.ToLower(strings.TrimSpace(d))
+			if len(d) < 3 || !contains(weekdays, d[:3]) {
+				add(fmt.Sprintf("%sdays[%d]", prefix, j), fmt.Errorf("無效的星期 %q", s.Days[j]))
+			}
+		}
+	}
+	return errs
+}
+
+// validateRules 檢查規則的動作類型與各動作必填的欄位，route 的通知後端需存在、forward 的網址需有效；正規表示式在建立規則引擎時編譯
+func validateRules(rules []Rule, notifiers map[string]NotifierConfig) ValidationError {
+	var errs ValidationError
+	add := func(i int, field string, err error) {
+		errs = append(errs, FieldError{Field: fmt.Sprintf("rules[%d].action.%s", i, field), Message: err.Error()})
+	}
+
+	for i, r := range rules {
+		a := r.Action
+		switch {
+		case !contains(RuleActionTypes, a.Type):
+			add(i, "type", fmt.Errorf("未知的動作 %q", a.Type))
+		case a.Type == "priority" && a.Priority == "":
+			add(i, "priority", fmt.Errorf("priority 動作需要 priority"))
+		case a.Type == "rewrite_title" && a.Title == "":
+			add(i, "title", fmt.Errorf("rewrite_title 動作需要 title"))
+		case a.Type == "route" && a.Notifier == "":
+			add(i, "notifier", fmt.Errorf("route 動作需要 notifier"))
+		case a.Type == "route" && !knownNotifier(notifiers, a.Notifier):
+			add(i, "notifier", fmt.Errorf("不在 notifiers 中: %q", a.Notifier))
+		case a.Type == "forward":
+			if err := ValidateDomain(a.URL); err != nil {
+				add(i, "url", err)
+			}
+		}
+	}
+	return errs
+}
+
+// knownNotifier 判斷名稱是否為 notifiers 中的通知後端或內建的 toast
+func knownNotifier(notifiers map[string]NotifierConfig, name string) bool {
+	_, ok := notifiers[name]
+	return ok || name == "toast"
+}
+
+// validateNotifiers 檢查通知後端的類型與各類型必填的欄位
+func validateNotifiers(notifiers map[string]NotifierConfig) ValidationError {
+	var errs ValidationError
+	add := func(name, field string, err error) {
+		errs = append(errs, FieldError{Field: fmt.Sprintf("notifiers.%s.%s", name, field), Message: err.Error()})
+	}
+
+	names := make([]string, 0, len(notifiers))
+	for name := range notifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		n := notifiers[name]
+		switch {
+		case !contains(NotifierTypes, n.Type):
+			add(name, "type", fmt.Errorf("未知的類型 %q", n.Type))
+		case n.Type == "webhook":
+			if err := ValidateDomain(n.URL); err != nil {
+				add(name, "url", err)
+			}
+		case n.Type == "command" && len(n.Command) == 0:
+			add(name, "command", fmt.Errorf("command 需要 command"))
+		}
+	}
+	return errs
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestValidateFieldErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		cfg    Config
+		fields []string
+	}{
+		{"valid", Config{Domain: "http://localhost:9204", Interval: 5}, nil},
+		{"missing domain", Config{Interval: 5}, []string{"domain"}},
+		{"domain without scheme", Config{Domain: "localhost:9204", Interval: 5}, []string{"domain"}},
+		{"negative interval", Config{Domain: "https://api.example.com", Interval: -1}, []string{"interval"}},
+		{"interval too large", Config{Domain: "https://api.example.com", Interval: 3601}, []string{"interval"}},
+		{"both", Config{Domain: "ftp://x", Interval: 0}, []string{"domain", "interval"}},
+		{"ack mode", Config{Domain: "http://x", Interval: 5, AckMode: "later"}, []string{"ack_mode"}},
+		{"profiles", Config{Domain: "http://x", Interval: 5, Profiles: []Profile{
+			{Name: "ok", Domain: "http://y"},
+			{Name: "ok", Domain: "y", Interval: 9999},
+		}}, []string{"profiles[1].name", "profiles[1].domain", "profiles[1].interval"}},
+		{"rules and notifiers", Config{Domain: "http://x", Interval: 5,
+			Rules: []Rule{
+				{Action: RuleAction{Type: "drop"}},
+				{Action: RuleAction{Type: "priority"}},
+				{Action: RuleAction{Type: "show"}},
+				{Action: RuleAction{Type: "route", Notifier: "pager"}},
+				{Action: RuleAction{Type: "route", Notifier: "toast"}},
+				{Action: RuleAction{Type: "route", Notifier: "log"}},
+				{Action: RuleAction{Type: "forward", URL: "hooks.example.com"}},
+				{Action: RuleAction{Type: "forward", URL: "https://hooks.example.com/n"}},
+			},
+			Notifiers: map[string]NotifierConfig{
+				"hook": {Type: "webhook", URL: "example.com/hook"},
+				"cmd":  {Type: "command"},
+				"log":  {Type: "console"},
+			},
+		}, []string{"rules[0].action.type", "rules[1].action.priority", "rules[3].action.notifier", "rules[6].action.url",
+			"notifiers.hook.url", "notifiers.cmd.command"}},
+		{"quiet hours", Config{Domain: "http://x", Interval: 5, QuietHours: QuietHours{
+			Timezone:  "Mars/Olympus",
+			Schedules: []QuietSchedule{{Days: []string{"Monday", "xy"}, Start: "22:00", End: "7am"}},
+		}}, []string{"quiet_hours.timezone", "quiet_hours.schedules[0].end", "quiet_hours.schedules[0].days[1]"}},
+		{"history too large", Config{Domain: "http://x", Interval: 5, History: History{MaxRecords: MaxHistoryRecords + 1}},
+			[]string{"history.max_records"}},
+		{"counts", Config{Domain: "http://x", Interval: 5,
+			Dedup:      Dedup{Key: []string{"title", "body"}, Window: -1},
+			History:    History{MaxRecords: -1},
+			Escalation: []Escalation{{EscalateAfter: 10}, {EscalateAfter: 10, Notifier: "pager"}, {EscalateAfter: 10, Notifier: "toast"}},
+		}, []string{"dedup.key[1]", "dedup.window", "history.max_records", "escalation[0].notifier", "escalation[1].notifier"}},
+	}
+	for _, c := range cases {
+		err := c.cfg.Validate()
+		if len(c.fields) == 0 {
+			if err != nil {
+				t.Errorf("%s: Validate() = %v, want nil", c.name, err)
+			}
+			continue
+		}
+
+		var verr ValidationError
+		if !errors.As(err, &verr) {
+			t.Errorf("%s: Validate() = %v, want ValidationError", c.name, err)
+			continue
+		}
+		if len(verr) != len(c.fields) {
+			t.Errorf("%s: got %d field errors %v, want %v", c.name, len(verr), verr, c.fields)
+		}
+		for _, f := range c.fields {
+			if verr.Field(f) == "" {
+				t.Errorf("%s: missing error for %s in %v", c.name, f, verr)
+			}
+		}
+	}
+}
+
+func TestLoadRejectsInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"domain": "localhost:9204", "interval": -5, "project": "crm", "rules": [{"name": "r"}]}`), 0644)
+	cfg, err := Load(path)
+	var verr ValidationError
+	if !errors.As(err, &verr) || verr.Field("domain") == "" || verr.Field("interval") == "" {
+		t.Fatalf("Load() error = %v, want domain and interval field errors", err)
+	}
+	// 欄位無效時仍返回解碼後的設定，呼叫端不必改用預設值
+	if cfg == nil || cfg.Project != "crm" || len(cfg.Rules) != 1 {
+		t.Fatalf("Load() config = %+v, want decoded config", cfg)
+	}
+
+	// 未設定的 interval 沿用 schema 的預設值
+	os.WriteFile(path, []byte(`{"domain": "http://localhost:9204"}`), 0644)
+	cfg, err = Load(path)
+	if err != nil || cfg.Interval != 5 {
+		t.Fatalf("Load() = %+v, %v, want interval 5", cfg, err)
+	}
+}
+
+func TestSaveReplacesFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	cfg := Default()
+	cfg.Project = "crm"
+	if err := Save(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Project = "web"
+	if err := Save(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil || loaded.Project != "web" {
+		t.Fatalf("Load() = %+v, %v, want project web", loaded, err)
+	}
+	// 暫存檔在重新命名後不應留在目錄中
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory has %d files, want only config.json", len(entries))
+	}
+}
+
+// schemaNode 是 JSON Schema 中測試用到的部分
+type schemaNode struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 interface{}            `json:"type"`
+	Required             []string               `json:"required"`
+	Properties           map[string]*schemaNode `json:"properties"`
+	AdditionalProperties interface{}            `json:"additionalProperties"`
+	Items                *schemaNode            `json:"items"`
+	Enum                 []string               `json:"enum"`
+	Examples             []string               `json:"examples"`
+	Default              interface{}            `json:"default"`
+	Minimum              int                    `json:"minimum"`
+	Maximum              int                    `json:"maximum"`
+	Definitions          map[string]*schemaNode `json:"definitions"`
+}
+
+// TestSchemaInSync 確認驗證規則與 shared/config/schema.json 一致
+func TestSchemaInSync(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "..", "shared", "config", "schema.json"))
+	if err != nil {
+		t.Skipf("找不到 schema: %v", err)
+	}
+
+	var schema schemaNode
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	resolve := func(n *schemaNode) *schemaNode {
+		if n != nil && strings.HasPrefix(n.Ref, "#/definitions/") {
+			return schema.Definitions[strings.TrimPrefix(n.Ref, "#/definitions/")]
+		}
+		return n
+	}
+
+	iv := schema.Properties["interval"]
+	if iv.Minimum != MinInterval || iv.Maximum != MaxInterval {
+		t.Errorf("schema interval range %d-%d, want %d-%d", iv.Minimum, iv.Maximum, MinInterval, MaxInterval)
+	}
+	if mr := schema.Definitions["history"].Properties["max_records"]; mr == nil || mr.Maximum != MaxHistoryRecords {
+		t.Errorf("schema history.max_records maximum should be %d", MaxHistoryRecords)
+	}
+	if iv.Default != float64(Default().Interval) {
+		t.Errorf("schema interval default %d, want %d", iv.Default, Default().Interval)
+	}
+
+	required := false
+	for _, f := range schema.Required {
+		required = required || f == "domain"
+	}
+	if !required || ValidateDomain("") == nil {
+		t.Errorf("domain must be required by both schema and ValidateDomain")
+	}
+	for _, ex := range schema.Properties["domain"].Examples {
+		if err := ValidateDomain(ex); err != nil {
+			t.Errorf("ValidateDomain(%q) = %v, schema example should be valid", ex, err)
+		}
+	}
+
+	// 每個結構的 JSON 欄位都要列在 schema 中，且 schema 不接受未知欄位
+	var walk func(path string, typ reflect.Type, node *schemaNode)
+	walk = func(path string, typ reflect.Type, node *schemaNode) {
+		node = resolve(node)
+		if node == nil {
+			t.Errorf("schema 缺少 %s", path)
+			return
+		}
+		switch typ.Kind() {
+		case reflect.Slice:
+			walk(path+"[]", typ.Elem(), node.Items)
+		case reflect.Map:
+			if sub, ok := node.AdditionalProperties.(map[string]interface{}); ok && typ.Elem().Kind() == reflect.Struct {
+				raw, _ := json.Marshal(sub)
+				var elem schemaNode
+				json.Unmarshal(raw, &elem)
+				walk(path+".*", typ.Elem(), &elem)
+			}
+		case reflect.Struct:
+			if node.AdditionalProperties != false {
+				t.Errorf("schema %s 應設定 additionalProperties: false", path)
+			}
+			for i := 0; i < typ.NumField(); i++ {
+				name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+				if name == "" || name == "-" {
+					continue
+				}
+				walk(path+"."+name, typ.Field(i).Type, node.Properties[name])
+			}
+		}
+	}
+	walk("config", reflect.TypeOf(Config{}), &schema)
+
+	enums := []struct {
+		name string
+		node *schemaNode
+		want []string
+	}{
+		{"ack_mode", schema.Properties["ack_mode"], []string{AckOnDisplay, AckOnClick, AckManual}},
+		{"rule action type", schema.Definitions["rule"].Properties["action"].Properties["type"], RuleActionTypes},
+		{"notifier type", schema.Definitions["notifier"].Properties["type"], NotifierTypes},
+		{"dedup key", schema.Definitions["dedup"].Properties["key"].Items, DedupKeys},
+	}
+	for _, e := range enums {
+		got := append([]string(nil), e.node.Enum...)
+		want := append([]string(nil), e.want...)
+		sort.Strings(got)
+		sort.Strings(want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("schema %s enum %v, want %v", e.name, got, want)
+		}
+	}
+}
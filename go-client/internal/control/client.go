@@ -0,0 +1,191 @@
+package control
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"windows-notification/internal/config"
+	"windows-notification/internal/history"
+	"windows-notification/internal/jsonfile"
+)
+
+// ErrNotRunning 代表找不到執行中的程式（未啟動或未開啟控制 API）
+var ErrNotRunning = errors.New("找不到執行中的程式，請確認程式已啟動且 control.enabled 為 true")
+
+// Error 代表控制 API 返回的錯誤
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("控制 API 回應錯誤: %d (%s)", e.StatusCode, e.Message)
+}
+
+// Client 是控制 API 的客戶端
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// Dial 讀取 statePath 中的連線資訊並確認程式仍在執行
+func Dial(statePath string) (*Client, error) {
+	var state State
+	if err := jsonfile.Load(statePath, &state); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotRunning
+		}
+		return nil, err
+	}
+	if state.Address == "" {
+		return nil, ErrNotRunning
+	}
+
+	c := &Client{
+		baseURL:    "http://" + state.Address,
+		token:      state.Token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	// 程式異常結束時連線資訊不會被移除
+	if err := c.Health(); err != nil {
+		return nil, ErrNotRunning
+	}
+	return c, nil
+}
+
+// call 送出請求並將回應解析到 out；out 為 nil 時忽略回應內容
+func (c *Client) call(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("控制 API 請求失敗: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusMultiStatus {
+		var e struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&e)
+		return &Error{StatusCode: resp.StatusCode, Message: e.Error}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("無法解析控制 API 回應: %w", err)
+	}
+	return nil
+}
+
+// Health 確認程式是否在執行
+func (c *Client) Health() error {
+	return c.call(http.MethodGet, "/health", nil, nil)
+}
+
+// Status 返回執行中程式的狀態
+func (c *Client) Status() (Status, error) {
+	var s Status
+	err := c.call(http.MethodGet, "/status", nil, &s)
+	return s, err
+}
+
+// Start 開始監控
+func (c *Client) Start() (Status, error) {
+	var s Status
+	err := c.call(http.MethodPost, "/start", nil, &s)
+	return s, err
+}
+
+// Stop 停止監控
+func (c *Client) Stop() (Status, error) {
+	var s Status
+	err := c.call(http.MethodPost, "/stop", nil, &s)
+	return s, err
+}
+
+// Pause 開啟勿擾 minutes 分鐘，0 代表到明天早上；返回勿擾結束時間
+func (c *Client) Pause(minutes int) (time.Time, error) {
+	var out struct {
+		PausedUntil time.Time `json:"paused_until"`
+	}
+	err := c.call(http.MethodPost, "/pause", map[string]int{"minutes": minutes}, &out)
+	return out.PausedUntil, err
+}
+
+// Resume 結束手動勿擾
+func (c *Client) Resume() (Status, error) {
+	var s Status
+	err := c.call(http.MethodPost, "/resume", nil, &s)
+	return s, err
+}
+
+// History 查詢通知歷史；query 為搜尋語法，limit 為 0 使用伺服器預設值
+func (c *Client) History(query string, unread bool, limit int) ([]history.Record, error) {
+	v := url.Values{}
+	if query != "" {
+		v.Set("q", query)
+	}
+	if unread {
+		v.Set("unread", "true")
+	}
+	if limit > 0 {
+		v.Set("limit", strconv.Itoa(limit))
+	}
+
+	var records []history.Record
+	err := c.call(http.MethodGet, "/history?"+v.Encode(), nil, &records)
+	return records, err
+}
+
+// Ack 確認通知，返回每一則的結果
+func (c *Client) Ack(ids ...string) ([]AckResult, error) {
+	var results []AckResult
+	err := c.call(http.MethodPost, "/ack", map[string][]string{"ids": ids}, &results)
+	return results, err
+}
+
+// Config 返回執行中程式的設定（API 金鑰與權杖已遮蔽）
+func (c *Client) Config() (config.Config, error) {
+	var cfg config.Config
+	err := c.call(http.MethodGet, "/config", nil, &cfg)
+	return cfg, err
+}
+
+// UpdateConfig 修改設定並返回修改後的設定
+func (c *Client) UpdateConfig(u ConfigUpdate) (config.Config, error) {
+	var cfg config.Config
+	err := c.call(http.MethodPatch, "/config", u, &cfg)
+	return cfg, err
+}
+
+// StatePath 返回設定對應的連線資訊檔路徑
+func StatePath(cfg *config.Config) string {
+	return cfg.DataPath(StateFile)
+}
@@ -0,0 +1,402 @@
+package control
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"windows-notification/internal/config"
+	"windows-notification/internal/history"
+	"windows-notification/internal/jsonfile"
+	"windows-notification/internal/logger"
+	"windows-notification/internal/search"
+)
+
+// StateFile 是記錄控制 API 位址與權杖的檔名，位於資料目錄中
+const StateFile = "control.json"
+
+// DefaultAddress 是預設監聽位址（本機隨機埠）
+const DefaultAddress = "127.0.0.1:0"
+
+// historyLimit 是歷史查詢未指定筆數時的預設值
+const historyLimit = 50
+
+// maxBody 是請求內容的大小上限
+const maxBody = 1 << 20
+
+// Status 是執行中程式的狀態
+type Status struct {
+	Monitoring  bool      `json:"monitoring"`
+	Health      string    `json:"health"`
+	LastCheck   time.Time `json:"last_check"`
+	LastError   string    `json:"last_error,omitempty"`
+	Queued      int       `json:"queued"`
+	Outbox      int       `json:"outbox"`
+	Quiet       bool      `json:"quiet"`        // 勿擾中（排程或手動暫停）
+	PausedUntil time.Time `json:"paused_until"` // 手動勿擾的結束時間，未暫停時為零值
+	Unread      int       `json:"unread"`
+	Project     string    `json:"project"`
+	Interval    int       `json:"interval"`
+
+	Profiles []ProfileStatus `json:"profiles,omitempty"` // 有多個設定檔時列出各自的狀態
+}
+
+// ProfileStatus 是單一設定檔的監控狀態
+type ProfileStatus struct {
+	Name       string    `json:"name"`
+	Enabled    bool      `json:"enabled"`
+	Monitoring bool      `json:"monitoring"`
+	Health     string    `json:"health"`
+	LastError  string    `json:"last_error,omitempty"`
+	Failures   int       `json:"failures"`
+	NextCheck  time.Time `json:"next_check"`
+}
+
+// ConfigUpdate 代表可透過控制 API 修改的設定，nil 的欄位不變更
+type ConfigUpdate struct {
+	Domain   *string `json:"domain,omitempty"`
+	Project  *string `json:"project,omitempty"`
+	Interval *int    `json:"interval,omitempty"`
+	Debug    *bool   `json:"debug,omitempty"`
+}
+
+// Records 提供通知歷史查詢，由 history.Store 或合併多個設定檔的視窗實作
+type Records interface {
+	Query(f history.Filter) []history.Record
+}
+
+// Controller 由執行中的程式實作；開始、停止與設定應走與介面按鈕相同的流程
+type Controller interface {
+	Status() Status
+	Start() error
+	Stop() error
+	Pause(minutes int) time.Time // minutes 為 0 時暫停到明天早上
+	Resume()
+	Ack(id string) error // id 可為 "設定檔/ID"，未指定設定檔時為 default
+	Config() config.Config
+	UpdateConfig(u ConfigUpdate) error
+}
+
+// State 是寫入資料目錄的連線資訊，供命令列客戶端找到執行中的程式
+type State struct {
+	Address string `json:"address"`
+	Token   string `json:"token"`
+	PID     int    `json:"pid"`
+}
+
+// AckResult 代表單一通知的確認結果
+type AckResult struct {
+	ID    string `json:"id"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Server 是只監聽本機的控制 API
+type Server struct {
+	listener   net.Listener
+	server     *http.Server
+	token      string
+	statePath  string
+	controller Controller
+	history    Records
+	logger     *logger.Logger
+}
+
+// Start 依設定啟動控制 API，並將位址與權杖寫入 statePath；records 可為 nil
+func Start(cfg config.Control, statePath string, controller Controller, records Records, log *logger.Logger) (*Server, error) {
+	address := cfg.Address
+	if address == "" {
+		address = DefaultAddress
+	}
+	if err := checkLoopback(address); err != nil {
+		return nil, err
+	}
+
+	token := cfg.Token
+	if token == "" {
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("無法產生權杖: %w", err)
+		}
+		token = hex.EncodeToString(buf)
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("無法啟動控制 API: %w", err)
+	}
+
+	s := &Server{
+		listener:   listener,
+		token:      token,
+		statePath:  statePath,
+		controller: controller,
+		history:    records,
+		logger:     log,
+	}
+	state := State{Address: listener.Addr().String(), Token: token, PID: os.Getpid()}
+	if err := jsonfile.Save(statePath, state); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("無法寫入控制 API 連線資訊: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/status", s.auth(http.MethodGet, s.handleStatus))
+	mux.HandleFunc("/start", s.auth(http.MethodPost, s.handleStart))
+	mux.HandleFunc("/stop", s.auth(http.MethodPost, s.handleStop))
+	mux.HandleFunc("/pause", s.auth(http.MethodPost, s.handlePause))
+	mux.HandleFunc("/resume", s.auth(http.MethodPost, s.handleResume))
+	mux.HandleFunc("/history", s.auth(http.MethodGet, s.handleHistory))
+	mux.HandleFunc("/ack", s.auth(http.MethodPost, s.handleAck))
+	mux.HandleFunc("/config", s.auth("", s.handleConfig))
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed && log != nil {
+			log.Errorf("控制 API 已停止: %v", err)
+		}
+	}()
+
+	if log != nil {
+		log.Infof("控制 API 已啟動: %s", listener.Addr())
+	}
+	return s, nil
+}
+
+// checkLoopback 確認監聽位址只限本機
+func checkLoopback(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("控制 API 位址無效: %w", err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("控制 API 只能監聽本機位址: %s", address)
+}
+
+// Addr 返回實際監聽的位址
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close 停止控制 API 並移除連線資訊
+func (s *Server) Close() error {
+	os.Remove(s.statePath)
+	return s.server.Close()
+}
+
+// auth 檢查權杖與 HTTP 方法；method 為空時由處理函式自行判斷
+func (s *Server) auth(method string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("權杖無效"))
+			return
+		}
+		if method != "" && r.Method != method {
+			w.Header().Set("Allow", method)
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("只接受 %s", method))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// writeJSON 輸出 JSON 回應
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError 以 {"error": "..."} 輸出錯誤
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// readJSON 解析請求內容，空內容時不變更 v
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("請求內容無效: %w", err)
+	}
+	return nil
+}
+
+// handleHealth 不需要權杖，只回報程式是否在執行
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true, "pid": os.Getpid()})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.controller.Status())
+}
+
+func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
+	if err := s.controller.Start(); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.controller.Status())
+}
+
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	if err := s.controller.Stop(); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.controller.Status())
+}
+
+// handlePause 依 {"minutes": N} 開啟勿擾，未指定或為 0 時暫停到明天早上
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Minutes int `json:"minutes"`
+	}
+	if err := readJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Minutes < 0 {
+		writeError(w, http.StatusBadRequest, errors.New("minutes 不可為負數"))
+		return
+	}
+	until := s.controller.Pause(req.Minutes)
+	writeJSON(w, http.StatusOK, map[string]time.Time{"paused_until": until})
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	s.controller.Resume()
+	writeJSON(w, http.StatusOK, s.controller.Status())
+}
+
+// handleHistory 查詢通知歷史；q 為搜尋語法，unread=true 只返回未讀
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if s.history == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("通知歷史無法使用"))
+		return
+	}
+
+	q := r.URL.Query()
+	limit := historyLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("limit 無效: %q", v))
+			return
+		}
+		limit = n
+	}
+	unread := q.Get("unread") == "true" || q.Get("unread") == "1"
+
+	var records []history.Record
+	if text := strings.TrimSpace(q.Get("q")); text != "" {
+		query, err := search.Parse(text, time.Now())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		for _, result := range search.Build(s.history.Query(history.Filter{})).Search(query, 0) {
+			records = append(records, result.Record)
+		}
+	} else {
+		records = s.history.Query(history.Filter{})
+	}
+
+	out := make([]history.Record, 0, len(records))
+	for _, rec := range records {
+		if unread && !rec.ReadAt.IsZero() {
+			continue
+		}
+		out = append(out, rec)
+		if limit > 0 && len(out) == limit {
+			break
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleAck 確認 {"ids": [...]} 中的通知，返回每一則的結果
+func (s *Server) handleAck(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if err := readJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(req.IDs) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("需要 ids"))
+		return
+	}
+
+	results := make([]AckResult, 0, len(req.IDs))
+	status := http.StatusOK
+	for _, id := range req.IDs {
+		result := AckResult{ID: id, OK: true}
+		if err := s.controller.Ack(id); err != nil {
+			result = AckResult{ID: id, Error: err.Error()}
+			status = http.StatusMultiStatus
+		}
+		results = append(results, result)
+	}
+	writeJSON(w, status, results)
+}
+
+// handleConfig 以 GET 讀取設定（API 金鑰與權杖會遮蔽），以 PATCH 修改部分設定
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPatch, http.MethodPut:
+		var u ConfigUpdate
+		if err := readJSON(w, r, &u); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.controller.UpdateConfig(u); err != nil {
+			var verr config.ValidationError
+			if errors.As(err, &verr) {
+				writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error(), "fields": verr})
+				return
+			}
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PATCH")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("只接受 GET 或 PATCH"))
+		return
+	}
+
+	cfg := s.controller.Config()
+	if cfg.APIKey != "" {
+		cfg.APIKey = "***"
+	}
+	if cfg.Control.Token != "" {
+		cfg.Control.Token = "***"
+	}
+	// 複製後再遮蔽，避免修改執行中的設定
+	cfg.Profiles = append([]config.Profile(nil), cfg.Profiles...)
+	for i := range cfg.Profiles {
+		if cfg.Profiles[i].APIKey != "" {
+			cfg.Profiles[i].APIKey = "***"
+		}
+	}
+	writeJSON(w, http.StatusOK, cfg)
+}
@@ -0,0 +1,159 @@
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"windows-notification/internal/api"
+	"windows-notification/internal/config"
+	"windows-notification/internal/jsonfile"
+)
+
+// 預設值
+const (
+	DefaultWindow = 60 * time.Second
+	// retention 是沒有新重複時保留狀態的時間
+	retention = 24 * time.Hour
+)
+
+// DefaultKey 是預設的去重欄位
+var DefaultKey = []string{"project", "title", "message"}
+
+// entry 代表一個去重鍵的狀態
+type entry struct {
+	LastShown  time.Time `json:"last_shown"`
+	Suppressed []string  `json:"suppressed"` // 已合併的通知 ID
+}
+
+// Deduper 在時間窗內合併相同的通知，狀態會保存到磁碟
+type Deduper struct {
+	mu      sync.Mutex
+	path    string
+	window  time.Duration
+	fields  []string
+	entries map[string]*entry
+}
+
+// New 建立去重器並載入先前保存的狀態
+func New(path string, cfg config.Dedup) (*Deduper, error) {
+	fields := cfg.Key
+	if len(fields) == 0 {
+		fields = DefaultKey
+	}
+	for _, f := range fields {
+		if _, err := field(api.Notification{}, f); err != nil {
+			return nil, err
+		}
+	}
+
+	window := DefaultWindow
+	if cfg.Window > 0 {
+		window = time.Duration(cfg.Window) * time.Second
+	}
+
+	d := &Deduper{
+		path:    path,
+		window:  window,
+		fields:  fields,
+		entries: make(map[string]*entry),
+	}
+	if err := jsonfile.Load(path, &d.entries); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return d, nil
+}
+
+// field 取得通知中用於去重的欄位值
+func field(n api.Notification, name string) (string, error) {
+	switch name {
+	case "project":
+		return n.Project, nil
+	case "title":
+		return n.Title, nil
+	case "message":
+		return n.Message, nil
+	case "type":
+		return n.Type, nil
+	case "priority":
+		return n.Priority, nil
+	case "repo":
+		return n.Repo, nil
+	case "branch":
+		return n.Branch, nil
+	default:
+		return "", fmt.Errorf("無效的去重欄位 %q", name)
+	}
+}
+
+// key 計算通知的去重鍵
+func (d *Deduper) key(n api.Notification) string {
+	values := make([]string, len(d.fields))
+	for i, f := range d.fields {
+		values[i], _ = field(n, f)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(values, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// IsDuplicate 判斷通知是否與時間窗內已顯示的通知重複；重複時會記錄下來
+func (d *Deduper) IsDuplicate(n api.Notification, now time.Time) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	e, ok := d.entries[d.key(n)]
+	if !ok || now.Sub(e.LastShown) >= d.window {
+		return false, nil
+	}
+
+	for _, id := range e.Suppressed {
+		if id == n.ID {
+			return true, nil
+		}
+	}
+	e.Suppressed = append(e.Suppressed, n.ID)
+	return true, d.save(now)
+}
+
+// Pending 返回下一則顯示時應合併的重複數量（不含本身）
+func (d *Deduper) Pending(n api.Notification) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if e, ok := d.entries[d.key(n)]; ok {
+		return len(e.Suppressed)
+	}
+	return 0
+}
+
+// Shown 記錄通知已顯示，並清除已合併的重複數量
+func (d *Deduper) Shown(n api.Notification, now time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.entries[d.key(n)] = &entry{LastShown: now}
+	return d.save(now)
+}
+
+// save 清除過期狀態後寫入磁碟（呼叫端需持有鎖）
+func (d *Deduper) save(now time.Time) error {
+	for k, e := range d.entries {
+		if now.Sub(e.LastShown) > retention {
+			delete(d.entries, k)
+		}
+	}
+	return jsonfile.Save(d.path, d.entries)
+}
+
+// Title 將合併數量附加到標題，例如 "Build failed (x4)"
+func Title(title string, suppressed int) string {
+	if suppressed == 0 {
+		return title
+	}
+	return fmt.Sprintf("%s (x%d)", title, suppressed+1)
+}
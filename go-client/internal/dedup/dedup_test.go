@@ -0,0 +1,80 @@
+package dedup
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"windows-notification/internal/api"
+	"windows-notification/internal/config"
+)
+
+func TestWindowAndCount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedup.json")
+	d, err := New(path, config.Dedup{Enabled: true, Window: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	n := func(id, title string) api.Notification {
+		return api.Notification{ID: id, Project: "web", Title: title, Message: "exit 1"}
+	}
+
+	steps := []struct {
+		notif     api.Notification
+		after     time.Duration
+		duplicate bool
+	}{
+		{n("1", "Build failed"), 0, false},
+		{n("2", "Build failed"), 10 * time.Second, true},
+		{n("2", "Build failed"), 20 * time.Second, true}, // 同一則通知再次查詢到時不重複計算
+		{n("3", "Build failed"), 30 * time.Second, true},
+		{n("4", "Deploy failed"), 30 * time.Second, false}, // 去重鍵不同
+		{n("5", "Build failed"), 60 * time.Second, false},  // 超過時間窗
+	}
+	for i, s := range steps {
+		now := start.Add(s.after)
+		got, err := d.IsDuplicate(s.notif, now)
+		if err != nil || got != s.duplicate {
+			t.Fatalf("step %d: IsDuplicate(%s) = %v, %v, want %v", i, s.notif.ID, got, err, s.duplicate)
+		}
+		if !got {
+			if i == len(steps)-1 {
+				// 時間窗結束後顯示的通知合併先前的重複
+				if p := d.Pending(s.notif); p != 2 || Title(s.notif.Title, p) != "Build failed (x3)" {
+					t.Errorf("Pending() = %d, title %q", p, Title(s.notif.Title, p))
+				}
+			}
+			d.Shown(s.notif, now)
+		}
+	}
+	if p := d.Pending(n("6", "Build failed")); p != 0 {
+		t.Errorf("Pending() after Shown = %d, want 0", p)
+	}
+}
+
+func TestStateSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedup.json")
+	cfg := config.Dedup{Enabled: true, Key: []string{"project", "type"}, Window: 300}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	d, err := New(path, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Shown(api.Notification{ID: "1", Project: "web", Type: "ci", Title: "a"}, now)
+	d.IsDuplicate(api.Notification{ID: "2", Project: "web", Type: "ci", Title: "b"}, now.Add(time.Minute))
+
+	d, err = New(path, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dup, _ := d.IsDuplicate(api.Notification{ID: "3", Project: "web", Type: "ci", Title: "c"}, now.Add(2*time.Minute))
+	if !dup || d.Pending(api.Notification{Project: "web", Type: "ci"}) != 2 {
+		t.Errorf("after restart: duplicate = %v, pending = %d, want true and 2", dup, d.Pending(api.Notification{Project: "web", Type: "ci"}))
+	}
+
+	if _, err := New(path, config.Dedup{Key: []string{"body"}}); err == nil {
+		t.Error("New() accepted an unknown key field")
+	}
+}
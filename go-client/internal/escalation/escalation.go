@@ -0,0 +1,86 @@
+package escalation
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"windows-notification/internal/api"
+	"windows-notification/internal/config"
+	"windows-notification/internal/ledger"
+)
+
+// Step 代表需要執行的升級步驟
+type Step int
+
+const (
+	StepNone     Step = iota
+	StepRenotify      // 以強調樣式重新顯示
+	StepEscalate      // 送到次要通道
+)
+
+// Policies 依專案與優先權選擇升級政策
+type Policies struct {
+	policies []config.Escalation
+}
+
+// New 驗證升級政策設定
+func New(cfgs []config.Escalation) (*Policies, error) {
+	for i, p := range cfgs {
+		for _, pattern := range []string{p.Project, p.Priority} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("升級政策 #%d: 無效的萬用字元 %q: %w", i+1, pattern, err)
+			}
+		}
+		if p.EscalateAfter > 0 && p.Notifier == "" {
+			return nil, fmt.Errorf("升級政策 #%d: escalate_after 需要 notifier", i+1)
+		}
+	}
+	return &Policies{policies: cfgs}, nil
+}
+
+// For 返回第一個符合通知的政策
+func (p *Policies) For(n api.Notification) (config.Escalation, bool) {
+	for _, policy := range p.policies {
+		if !match(policy.Project, n.Project) || !match(policy.Priority, n.Priority) {
+			continue
+		}
+		return policy, true
+	}
+	return config.Escalation{}, false
+}
+
+// RequiresAck 判斷通知是否需要使用者明確確認
+func (p *Policies) RequiresAck(n api.Notification) bool {
+	_, ok := p.For(n)
+	return ok
+}
+
+// match 以萬用字元比對，空字串代表不限制
+func match(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, value)
+	return ok
+}
+
+// Next 返回投遞記錄目前應執行的升級步驟
+func (p *Policies) Next(e ledger.Entry, now time.Time) (Step, config.Escalation) {
+	if !e.UserAckedAt.IsZero() {
+		return StepNone, config.Escalation{}
+	}
+	policy, ok := p.For(e.Notification)
+	if !ok {
+		return StepNone, config.Escalation{}
+	}
+
+	waited := now.Sub(e.ShownAt)
+	if !e.Escalated && policy.EscalateAfter > 0 && waited >= time.Duration(policy.EscalateAfter)*time.Minute {
+		return StepEscalate, policy
+	}
+	if !e.Renotified && policy.RenotifyAfter > 0 && waited >= time.Duration(policy.RenotifyAfter)*time.Minute {
+		return StepRenotify, policy
+	}
+	return StepNone, policy
+}
@@ -0,0 +1,143 @@
+package gui
+
+import (
+	"strconv"
+	"time"
+
+	"windows-notification/internal/config"
+	"windows-notification/internal/control"
+	"windows-notification/internal/monitor"
+)
+
+// remoteControl 讓控制 API 透過與介面按鈕相同的流程操作視窗
+type remoteControl struct {
+	aw *AppWindow
+}
+
+// startControl 依設定啟動本機控制 API
+func (aw *AppWindow) startControl() {
+	if !aw.cfg.Control.Enabled {
+		return
+	}
+	var records control.Records
+	if aw.hasHistory() {
+		records = aw
+	}
+	server, err := control.Start(aw.cfg.Control, control.StatePath(aw.cfg), remoteControl{aw}, records, aw.logger)
+	if err != nil {
+		if aw.logger != nil {
+			aw.logger.Errorf("%v，控制 API 已停用", err)
+		}
+		return
+	}
+	aw.control = server
+}
+
+// Status 以 default 設定檔為主，佇列與未讀數為所有設定檔的合計，連線狀態取最差的一個
+func (c remoteControl) Status() control.Status {
+	aw := c.aw
+	now := time.Now()
+	status := aw.engine.Status()
+	quiet := aw.engine.Quiet()
+
+	s := control.Status{
+		Monitoring:  aw.isStarted(),
+		Health:      string(status.Health),
+		LastCheck:   status.LastCheck,
+		LastError:   status.LastError,
+		Quiet:       quiet.Active(now),
+		PausedUntil: quiet.PausedUntil(now),
+		Unread:      aw.unread(),
+		Project:     aw.cfg.Project,
+		Interval:    aw.cfg.Interval,
+	}
+	for _, p := range aw.profiles {
+		ps := p.engine.Status()
+		s.Queued += ps.Queued
+		s.Outbox += ps.Outbox
+		if p != aw.profiles[0] && ps.Running && healthRank(ps.Health) > healthRank(monitor.Health(s.Health)) {
+			s.Health = string(ps.Health)
+			s.LastError = ps.LastError
+		}
+		if len(aw.profiles) > 1 {
+			s.Profiles = append(s.Profiles, control.ProfileStatus{
+				Name:       p.name,
+				Enabled:    p.enabled(),
+				Monitoring: ps.Running,
+				Health:     string(ps.Health),
+				LastError:  ps.LastError,
+				Failures:   ps.Failures,
+				NextCheck:  ps.NextCheck,
+			})
+		}
+	}
+	return s
+}
+
+// healthRank 依嚴重程度排序連線狀態
+func healthRank(h monitor.Health) int {
+	switch h {
+	case monitor.HealthDown:
+		return 3
+	case monitor.HealthDegraded:
+		return 2
+	case monitor.HealthOK:
+		return 1
+	}
+	return 0
+}
+
+func (c remoteControl) Start() error {
+	return c.aw.start()
+}
+
+func (c remoteControl) Stop() error {
+	return c.aw.stop()
+}
+
+func (c remoteControl) Pause(minutes int) time.Time {
+	return c.aw.pause(minutes)
+}
+
+func (c remoteControl) Resume() {
+	c.aw.resume()
+}
+
+func (c remoteControl) Ack(id string) error {
+	p, id := c.aw.splitProfileID(id)
+	return p.engine.Ack(id)
+}
+
+func (c remoteControl) Config() config.Config {
+	return *c.aw.cfg
+}
+
+// UpdateConfig 更新設定欄位後以「Save Config」相同的流程儲存；
+// 先以設定副本檢查，避免無效的值留在視窗的欄位中
+func (c remoteControl) UpdateConfig(u control.ConfigUpdate) error {
+	aw := c.aw
+	next := *aw.cfg
+	if u.Domain != nil {
+		next.Domain = *u.Domain
+	}
+	if u.Interval != nil {
+		next.Interval = *u.Interval
+	}
+	if err := next.Validate(); err != nil {
+		return err
+	}
+
+	if u.Domain != nil {
+		aw.domainEntry.SetText(*u.Domain)
+	}
+	if u.Project != nil {
+		aw.projectEntry.SetText(*u.Project)
+	}
+	if u.Interval != nil {
+		aw.intervalEntry.SetText(strconv.Itoa(*u.Interval))
+	}
+	if u.Debug != nil {
+		aw.debugCheck.SetChecked(*u.Debug)
+	}
+	return aw.saveConfig()
+}
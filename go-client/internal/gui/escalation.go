@@ -0,0 +1,92 @@
+package gui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"windows-notification/internal/ledger"
+)
+
+// showUnacknowledged 列出需要確認但使用者尚未確認的通知
+func (aw *AppWindow) showUnacknowledged() {
+	type unacked struct {
+		profile *profile
+		ledger.Entry
+	}
+
+	var entries []unacked
+	canSnooze := false
+	for _, p := range aw.profiles {
+		if p.stores.Ledger == nil {
+			continue
+		}
+		canSnooze = canSnooze || p.engine.CanSnooze()
+		for _, e := range p.stores.Ledger.AwaitingUserAck() {
+			if p.engine.RequiresAck(e) {
+				entries = append(entries, unacked{p, e})
+			}
+		}
+	}
+	selected := -1
+
+	list := widget.NewList(
+		func() int {
+			return len(entries)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			e := entries[id]
+			obj.(*widget.Label).SetText(fmt.Sprintf("%s %s#%s [%s] %s", e.ShownAt.Format("01-02 15:04"), aw.profilePrefix(e.profile.name), e.ID, e.Notification.Priority, e.Notification.Title))
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		selected = id
+	}
+
+	ackBtn := widget.NewButton("Acknowledge", func() {
+		if selected < 0 || selected >= len(entries) {
+			return
+		}
+		e := entries[selected]
+		if err := e.profile.engine.Ack(e.ID); err != nil {
+			dialog.ShowError(err, aw.window)
+			return
+		}
+		entries = append(entries[:selected], entries[selected+1:]...)
+		selected = -1
+		list.UnselectAll()
+		list.Refresh()
+	})
+
+	snoozeSelect := widget.NewSelect(snoozeLabels(), func(label string) {
+		if selected < 0 || selected >= len(entries) || label == "" {
+			return
+		}
+		e := entries[selected]
+		if err := e.profile.engine.SnoozeByID(e.ID, snoozeDelay(label)); err != nil {
+			dialog.ShowError(err, aw.window)
+			return
+		}
+		entries = append(entries[:selected], entries[selected+1:]...)
+		selected = -1
+		list.UnselectAll()
+		list.Refresh()
+	})
+	snoozeSelect.PlaceHolder = "Snooze..."
+
+	// 沒有設定檔可以延後時不顯示延後選項
+	buttons := container.NewHBox(ackBtn)
+	if canSnooze {
+		buttons.Add(snoozeSelect)
+	}
+	content := container.NewBorder(nil, buttons, nil, nil, list)
+	d := dialog.NewCustom("Unacknowledged", "Close", content, aw.window)
+	d.Resize(fyne.NewSize(550, 400))
+	d.Show()
+}
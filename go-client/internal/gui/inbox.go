@@ -0,0 +1,324 @@
+package gui
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"windows-notification/internal/history"
+)
+
+// inboxLimit 是收件匣最多顯示的通知數
+const inboxLimit = 500
+
+// inboxColumn 是收件匣的欄位名稱與寬度
+type inboxColumn struct {
+	title string
+	width float32
+}
+
+// inboxColumns 是收件匣的欄位；Profile 只在有多個設定檔時顯示
+var inboxColumns = []inboxColumn{
+	{"Time", 110},
+	{"Profile", 90},
+	{"Project", 120},
+	{"Priority", 80},
+	{"Title", 360},
+}
+
+// recordKey 返回歷史記錄在所有設定檔中唯一的鍵
+func recordKey(r history.Record) string {
+	return r.Profile + "/" + r.ID
+}
+
+// recordDetail 返回歷史記錄的詳細資訊
+func (aw *AppWindow) recordDetail(r history.Record) string {
+	n := r.Notification
+	header := fmt.Sprintf("#%s [%s] %s", n.ID, n.Project, n.Title)
+	if len(aw.profiles) > 1 {
+		header = fmt.Sprintf("%s  (profile: %s)", header, r.Profile)
+	}
+	lines := []string{
+		header,
+		fmt.Sprintf("優先權: %s  類型: %s  倉庫: %s  分支: %s", n.Priority, n.Type, n.Repo, n.Branch),
+		fmt.Sprintf("結果: %s  收到: %s", r.Outcome, r.ReceivedAt.Format("2006-01-02 15:04:05")),
+	}
+	for _, ts := range []struct {
+		label string
+		t     time.Time
+	}{{"顯示", r.DisplayedAt}, {"伺服器確認", r.AckedAt}, {"使用者確認", r.UserAckedAt}} {
+		if !ts.t.IsZero() {
+			lines = append(lines, fmt.Sprintf("%s: %s", ts.label, ts.t.Format("2006-01-02 15:04:05")))
+		}
+	}
+	if r.Error != "" {
+		lines = append(lines, "錯誤: "+r.Error)
+	}
+	if n.ActionURL != "" {
+		lines = append(lines, "網址: "+n.ActionURL)
+	}
+
+	if len(n.Metadata) > 0 {
+		keys := make([]string, 0, len(n.Metadata))
+		for k := range n.Metadata {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		lines = append(lines, "", "Metadata:")
+		for _, k := range keys {
+			lines = append(lines, fmt.Sprintf("  %s: %v", k, n.Metadata[k]))
+		}
+	}
+	return strings.Join(append(lines, "", n.Message), "\n")
+}
+
+// buildInbox 建立收件匣：上方為通知列表，下方為詳細內容與動作
+func (aw *AppWindow) buildInbox() fyne.CanvasObject {
+	columns := inboxColumns
+	if len(aw.profiles) < 2 {
+		columns = nil
+		for _, col := range inboxColumns {
+			if col.title != "Profile" {
+				columns = append(columns, col)
+			}
+		}
+	}
+
+	aw.inboxTable = widget.NewTable(
+		func() (int, int) {
+			aw.mu.Lock()
+			defer aw.mu.Unlock()
+			return len(aw.inboxRecords), len(columns)
+		},
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.TableCellID, obj fyne.CanvasObject) {
+			aw.mu.Lock()
+			if id.Row >= len(aw.inboxRecords) {
+				aw.mu.Unlock()
+				return
+			}
+			r := aw.inboxRecords[id.Row]
+			aw.mu.Unlock()
+
+			// 等待確認的通知在標題前加上標記
+			pending := aw.awaiting(r)
+
+			label := obj.(*widget.Label)
+			switch columns[id.Col].title {
+			case "Time":
+				label.Text = r.ReceivedAt.Format("01-02 15:04:05")
+			case "Profile":
+				label.Text = r.Profile
+			case "Project":
+				label.Text = r.Notification.Project
+			case "Priority":
+				label.Text = r.Notification.Priority
+			case "Title":
+				label.Text = r.Notification.Title
+				if pending {
+					label.Text = "● " + label.Text
+				}
+			}
+			// 未讀與等待確認的通知以粗體顯示
+			label.TextStyle.Bold = r.ReadAt.IsZero() || pending
+			label.Refresh()
+		},
+	)
+	aw.inboxTable.ShowHeaderRow = true
+	aw.inboxTable.UpdateHeader = func(id widget.TableCellID, obj fyne.CanvasObject) {
+		if id.Col >= 0 && id.Col < len(columns) {
+			obj.(*widget.Label).SetText(columns[id.Col].title)
+		}
+	}
+	for i, col := range columns {
+		aw.inboxTable.SetColumnWidth(i, col.width)
+	}
+
+	aw.inboxDetail = widget.NewLabel("")
+	aw.inboxDetail.Wrapping = fyne.TextWrapWord
+
+	aw.inboxTable.OnSelected = func(id widget.TableCellID) {
+		if aw.inboxSyncing {
+			return
+		}
+		aw.mu.Lock()
+		if id.Row >= len(aw.inboxRecords) {
+			aw.mu.Unlock()
+			return
+		}
+		r := &aw.inboxRecords[id.Row]
+		aw.inboxSelected = recordKey(*r)
+		// 等待確認的通知維持未讀，直到使用者按下確認
+		pending := aw.awaiting(*r)
+		if r.ReadAt.IsZero() && !pending {
+			r.ReadAt = time.Now()
+		}
+		record := *r
+		aw.mu.Unlock()
+
+		aw.inboxDetail.SetText(aw.recordDetail(record))
+		if p := aw.profile(record.Profile); !pending && p != nil && p.stores.History != nil {
+			if err := p.stores.History.MarkRead(record.ID, time.Now()); err != nil && aw.logger != nil {
+				aw.logger.Warnf("儲存通知歷史失敗: %v", err)
+			}
+		}
+		aw.inboxTable.Refresh()
+		aw.refreshInboxTitle()
+	}
+
+	ackBtn := widget.NewButton("Acknowledge", func() {
+		r, p, ok := aw.selectedRecord()
+		if !ok {
+			return
+		}
+		if err := p.engine.Ack(r.ID); err != nil {
+			dialog.ShowError(err, aw.window)
+		}
+	})
+	openBtn := widget.NewButton("Open URL", func() {
+		r, _, ok := aw.selectedRecord()
+		if !ok || r.Notification.ActionURL == "" {
+			return
+		}
+		u, err := url.Parse(r.Notification.ActionURL)
+		if err == nil {
+			err = aw.app.OpenURL(u)
+		}
+		if err != nil {
+			dialog.ShowError(err, aw.window)
+		}
+	})
+	unreadBtn := widget.NewButton("Mark Unread", func() {
+		r, p, ok := aw.selectedRecord()
+		if !ok {
+			return
+		}
+		aw.confirmMarkUnread(p, r.Notification, func() {
+			aw.clearInboxSelection()
+			aw.refreshInbox()
+		})
+	})
+	copyBtn := widget.NewButton("Copy", func() {
+		if r, _, ok := aw.selectedRecord(); ok {
+			aw.window.Clipboard().SetContent(aw.recordDetail(r))
+		}
+	})
+	deleteBtn := widget.NewButton("Delete Locally", func() {
+		r, p, ok := aw.selectedRecord()
+		if !ok {
+			return
+		}
+		dialog.ShowConfirm("Delete Locally", fmt.Sprintf("從本機歷史刪除通知 #%s？伺服器上的通知不受影響。", r.ID), func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			if err := p.stores.History.Delete(r.ID, time.Now()); err != nil {
+				dialog.ShowError(err, aw.window)
+			}
+			aw.clearInboxSelection()
+			aw.refreshInbox()
+		}, aw.window)
+	})
+
+	detail := container.NewBorder(nil, container.NewHBox(ackBtn, openBtn, unreadBtn, copyBtn, deleteBtn), nil, nil,
+		container.NewVScroll(aw.inboxDetail))
+	split := container.NewVSplit(aw.inboxTable, detail)
+	split.Offset = 0.6
+
+	aw.refreshInbox()
+	return split
+}
+
+// awaiting 判斷通知是否等待使用者確認
+func (aw *AppWindow) awaiting(r history.Record) bool {
+	p := aw.profile(r.Profile)
+	return p != nil && p.stores.Ledger != nil && p.stores.Ledger.Awaiting(r.ID)
+}
+
+// selectedRecord 返回收件匣中選取的通知與所屬設定檔
+func (aw *AppWindow) selectedRecord() (history.Record, *profile, bool) {
+	aw.mu.Lock()
+	key := aw.inboxSelected
+	aw.mu.Unlock()
+
+	name, id, ok := strings.Cut(key, "/")
+	if !ok {
+		return history.Record{}, nil, false
+	}
+	p := aw.profile(name)
+	if p == nil || p.stores.History == nil {
+		return history.Record{}, nil, false
+	}
+	r, ok := p.stores.History.Get(id)
+	r.Profile = p.name
+	return r, p, ok
+}
+
+// clearInboxSelection 取消收件匣的選取
+func (aw *AppWindow) clearInboxSelection() {
+	aw.mu.Lock()
+	aw.inboxSelected = ""
+	aw.mu.Unlock()
+
+	aw.inboxTable.UnselectAll()
+	aw.inboxDetail.SetText("")
+}
+
+// refreshInbox 重新載入收件匣，並保留原本選取的通知
+func (aw *AppWindow) refreshInbox() {
+	if aw.inboxTable == nil || !aw.hasHistory() {
+		return
+	}
+
+	records := aw.queryRecords(history.Filter{Limit: inboxLimit})
+	aw.mu.Lock()
+	aw.inboxRecords = records
+	selected, row := aw.inboxSelected, -1
+	for i, r := range records {
+		if recordKey(r) == selected {
+			row = i
+			break
+		}
+	}
+	aw.mu.Unlock()
+
+	aw.inboxTable.Refresh()
+	if selected != "" {
+		if row < 0 {
+			aw.clearInboxSelection()
+		} else {
+			// 新通知加入後選取的列會移動，重新選取但不觸發已讀
+			aw.inboxSyncing = true
+			aw.inboxTable.Select(widget.TableCellID{Row: row, Col: 0})
+			aw.inboxSyncing = false
+		}
+	}
+	aw.refreshInboxTitle()
+}
+
+// refreshInboxTitle 在收件匣分頁標題顯示未讀數量
+func (aw *AppWindow) refreshInboxTitle() {
+	defer aw.refreshTray()
+	if aw.inboxTab == nil || !aw.hasHistory() {
+		return
+	}
+	title := "Inbox"
+	if unread := aw.unread(); unread > 0 {
+		title = fmt.Sprintf("Inbox (%d)", unread)
+	}
+	if aw.inboxTab.Text != title {
+		aw.inboxTab.Text = title
+		aw.tabs.Refresh()
+	}
+}
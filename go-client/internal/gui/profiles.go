@@ -0,0 +1,261 @@
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"windows-notification/internal/actions"
+	"windows-notification/internal/config"
+	"windows-notification/internal/history"
+	"windows-notification/internal/monitor"
+)
+
+// profile 是一個伺服器設定檔的監控引擎與本機狀態
+type profile struct {
+	name   string
+	cfg    *config.Config
+	engine *monitor.Engine
+	stores monitor.Stores
+}
+
+// enabled 判斷設定檔是否啟用
+func (p *profile) enabled() bool {
+	return !p.cfg.Disabled
+}
+
+// profileActions 在通知 ID 前加上設定檔名稱，讓動作按鈕轉交給正確的引擎
+type profileActions struct {
+	server *actions.Server
+	prefix string
+}
+
+// URL 返回觸發動作的網址
+func (a profileActions) URL(action, id, arg string) string {
+	return a.server.URL(action, a.prefix+id, arg)
+}
+
+// openProfiles 為每個設定檔建立監控引擎；只有一個設定檔時通知標題不加上名稱
+func (aw *AppWindow) openProfiles(backends monitor.Backends) {
+	// 欄位無效的設定檔不監控，避免名稱中的路徑字元影響本機狀態目錄
+	var invalid config.ValidationError
+	errors.As(aw.configErr, &invalid)
+
+	all := aw.cfg.AllProfiles()
+	for i, p := range all {
+		if i > 0 && invalid.Has(fmt.Sprintf("profiles[%d].", i-1)) {
+			if aw.logger != nil {
+				aw.logger.Warnf("設定檔 profiles[%d] 無效，不監控", i-1)
+			}
+			continue
+		}
+		cfg, err := aw.cfg.ForProfile(p.Name)
+		if err != nil {
+			continue
+		}
+
+		stores := monitor.OpenStores(cfg, aw.logger)
+		opts := monitor.Options{
+			Config:   cfg,
+			Source:   aw.newClient(cfg),
+			Backends: backends,
+			Logger:   aw.logger,
+		}
+		if len(all) > 1 {
+			opts.Profile = p.Name
+		}
+		if aw.actions != nil {
+			prefix := ""
+			if p.Name != config.DefaultProfile {
+				prefix = p.Name + "/"
+			}
+			opts.Actions = profileActions{server: aw.actions, prefix: prefix}
+		}
+		stores.Apply(&opts)
+
+		aw.profiles = append(aw.profiles, &profile{
+			name:   p.Name,
+			cfg:    cfg,
+			engine: monitor.New(opts),
+			stores: stores,
+		})
+	}
+}
+
+// profile 依名稱返回設定檔，找不到時返回 nil
+func (aw *AppWindow) profile(name string) *profile {
+	for _, p := range aw.profiles {
+		if p.name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// splitProfileID 拆出 "名稱/ID" 形式中的設定檔；未指定設定檔時為 default
+func (aw *AppWindow) splitProfileID(id string) (*profile, string) {
+	if name, rest, ok := strings.Cut(id, "/"); ok {
+		if p := aw.profile(name); p != nil {
+			return p, rest
+		}
+	}
+	return aw.profiles[0], id
+}
+
+// forwardCommands 將命令列轉交的 ack 與 unread 交給通知所屬設定檔的引擎
+type forwardCommands struct {
+	aw *AppWindow
+}
+
+// engine 拆出 "名稱/ID" 形式中的設定檔；與動作按鈕不同，指定的設定檔不存在時返回錯誤
+func (c forwardCommands) engine(id string) (*monitor.Engine, string, error) {
+	name, rest, ok := strings.Cut(id, "/")
+	if !ok {
+		name, rest = config.DefaultProfile, id
+	}
+	if p := c.aw.profile(name); p != nil {
+		return p.engine, rest, nil
+	}
+	return nil, "", fmt.Errorf("設定檔 %s 未開啟", name)
+}
+
+func (c forwardCommands) AckNow(id string) error {
+	e, id, err := c.engine(id)
+	if err != nil {
+		return err
+	}
+	return e.AckNow(id)
+}
+
+func (c forwardCommands) MarkUnread(id string, showAgain bool) error {
+	e, id, err := c.engine(id)
+	if err != nil {
+		return err
+	}
+	return e.MarkUnreadByID(id, showAgain)
+}
+
+// profilePrefix 返回清單中標示設定檔的前綴，只有一個設定檔時為空
+func (aw *AppWindow) profilePrefix(name string) string {
+	if len(aw.profiles) < 2 {
+		return ""
+	}
+	return name + " "
+}
+
+// handleAction 將通知動作按鈕轉交給通知所屬設定檔的引擎
+func (aw *AppWindow) handleAction(action, id, arg string) error {
+	p, id := aw.splitProfileID(id)
+	return p.engine.HandleAction(action, id, arg)
+}
+
+// hasHistory 判斷是否有任一設定檔的通知歷史可用
+func (aw *AppWindow) hasHistory() bool {
+	for _, p := range aw.profiles {
+		if p.stores.History != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// queryRecords 合併所有設定檔的通知歷史，依收到時間由新到舊排序
+func (aw *AppWindow) queryRecords(f history.Filter) []history.Record {
+	var records []history.Record
+	for _, p := range aw.profiles {
+		if p.stores.History == nil {
+			continue
+		}
+		for _, r := range p.stores.History.Query(f) {
+			r.Profile = p.name
+			records = append(records, r)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].ReceivedAt.After(records[j].ReceivedAt)
+	})
+	if f.Limit > 0 && len(records) > f.Limit {
+		records = records[:f.Limit]
+	}
+	return records
+}
+
+// Query 讓控制 API 查詢合併後的通知歷史
+func (aw *AppWindow) Query(f history.Filter) []history.Record {
+	return aw.queryRecords(f)
+}
+
+// unread 返回所有設定檔的未讀通知數
+func (aw *AppWindow) unread() int {
+	n := 0
+	for _, p := range aw.profiles {
+		if p.stores.History != nil {
+			n += p.stores.History.Unread()
+		}
+	}
+	return n
+}
+
+// isStarted 判斷使用者是否已開始監控
+func (aw *AppWindow) isStarted() bool {
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+	return aw.started
+}
+
+// buildProfiles 建立設定檔的啟用切換，只有一個設定檔時不顯示
+func (aw *AppWindow) buildProfiles() fyne.CanvasObject {
+	if len(aw.profiles) < 2 {
+		return container.NewHBox()
+	}
+	box := container.NewHBox(widget.NewLabel("Profiles:"))
+	for _, p := range aw.profiles {
+		p := p
+		check := widget.NewCheck(p.name, func(on bool) {
+			aw.setProfileEnabled(p, on)
+		})
+		check.SetChecked(p.enabled())
+		box.Add(check)
+	}
+	return box
+}
+
+// setProfileEnabled 啟用或停用設定檔並儲存設定；監控中時立即開始或停止該設定檔的引擎
+func (aw *AppWindow) setProfileEnabled(p *profile, enabled bool) {
+	if p.enabled() == enabled {
+		return
+	}
+	p.cfg.Disabled = !enabled
+	if err := aw.cfg.SetProfileDisabled(p.name, !enabled); err != nil {
+		if aw.logger != nil {
+			aw.logger.Errorf("%v", err)
+		}
+		return
+	}
+	aw.writeConfig()
+
+	if aw.logger != nil {
+		if enabled {
+			aw.logger.Infof("已啟用設定檔: %s", p.name)
+		} else {
+			aw.logger.Infof("已停用設定檔: %s", p.name)
+		}
+	}
+
+	// 只有整體在監控中時才跟著開始，停用則一律停止
+	switch {
+	case enabled && aw.isStarted():
+		p.engine.Start()
+		aw.refreshStatusLabel()
+	case !enabled:
+		go func() {
+			p.engine.Stop()
+			aw.refreshStatusLabel()
+		}()
+	}
+}
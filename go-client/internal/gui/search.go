@@ -0,0 +1,108 @@
+package gui
+
+import (
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"windows-notification/internal/history"
+	"windows-notification/internal/search"
+)
+
+// searchLimit 是搜尋視窗最多顯示的結果數
+const searchLimit = 200
+
+// highlightSegments 將搜尋結果片段轉為 RichText，命中詞以粗體與主色標示
+func highlightSegments(spans []search.Span) []widget.RichTextSegment {
+	segments := make([]widget.RichTextSegment, 0, len(spans))
+	for _, span := range spans {
+		style := widget.RichTextStyleInline
+		if span.Match {
+			style.TextStyle = fyne.TextStyle{Bold: true}
+			style.ColorName = theme.ColorNamePrimary
+		}
+		segments = append(segments, &widget.TextSegment{Text: span.Text, Style: style})
+	}
+	return segments
+}
+
+// showSearch 在本機通知歷史中全文搜尋
+func (aw *AppWindow) showSearch() {
+	if !aw.hasHistory() {
+		dialog.ShowInformation("Search", "通知歷史無法使用", aw.window)
+		return
+	}
+
+	var results []search.Result
+	status := widget.NewLabel("")
+	detail := widget.NewLabel("")
+	detail.Wrapping = fyne.TextWrapWord
+
+	list := widget.NewList(
+		func() int {
+			return len(results)
+		},
+		func() fyne.CanvasObject {
+			title := widget.NewRichText()
+			title.Truncation = fyne.TextTruncateEllipsis
+			snippet := widget.NewRichText()
+			snippet.Truncation = fyne.TextTruncateEllipsis
+			return container.NewVBox(title, snippet)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			r := results[id]
+			rows := obj.(*fyne.Container).Objects
+
+			title := rows[0].(*widget.RichText)
+			prefix := &widget.TextSegment{
+				Text:  fmt.Sprintf("%s %s[%s] ", r.Record.ReceivedAt.Format("01-02 15:04"), aw.profilePrefix(r.Record.Profile), r.Record.Notification.Project),
+				Style: widget.RichTextStyleInline,
+			}
+			title.Segments = append([]widget.RichTextSegment{prefix}, highlightSegments(r.Title)...)
+			title.Refresh()
+
+			snippet := rows[1].(*widget.RichText)
+			snippet.Segments = highlightSegments(r.Snippet)
+			snippet.Refresh()
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		detail.SetText(aw.recordDetail(results[id].Record))
+	}
+
+	queryEntry := widget.NewEntry()
+	queryEntry.SetPlaceHolder("e.g. project:crm failed since:7d")
+	queryEntry.OnSubmitted = func(text string) {
+		query, err := search.Parse(text, time.Now())
+		if err != nil {
+			status.SetText(err.Error())
+			return
+		}
+
+		// 歷史最多保留 max_records 筆，每次搜尋重新建立索引即可
+		idx := search.Build(aw.queryRecords(history.Filter{}))
+		results = idx.Search(query, searchLimit)
+		status.SetText(fmt.Sprintf("%d 筆符合（共 %d 筆）", len(results), idx.Len()))
+		detail.SetText("")
+		list.UnselectAll()
+		list.Refresh()
+	}
+	searchBtn := widget.NewButton("Search", func() {
+		queryEntry.OnSubmitted(queryEntry.Text)
+	})
+
+	top := container.NewVBox(container.NewBorder(nil, nil, nil, searchBtn, queryEntry), status)
+	detailScroll := container.NewVScroll(detail)
+	detailScroll.SetMinSize(fyne.NewSize(0, 140))
+
+	content := container.NewBorder(top, detailScroll, nil, nil, list)
+	d := dialog.NewCustom("Search", "Close", content, aw.window)
+	d.Resize(fyne.NewSize(700, 550))
+	d.Show()
+	aw.window.Canvas().Focus(queryEntry)
+}
@@ -0,0 +1,109 @@
+package gui
+
+import (
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"windows-notification/internal/scheduler"
+)
+
+// snoozeLabels 返回延後選項的顯示文字
+func snoozeLabels() []string {
+	labels := make([]string, len(scheduler.Choices))
+	for i, c := range scheduler.Choices {
+		labels[i] = c.Label
+	}
+	return labels
+}
+
+// snoozeDelay 依顯示文字取得延後時間
+func snoozeDelay(label string) string {
+	for _, c := range scheduler.Choices {
+		if c.Label == label {
+			return c.Delay
+		}
+	}
+	return ""
+}
+
+// showSnoozed 列出延後中的通知，可立即送出或取消
+func (aw *AppWindow) showSnoozed() {
+	type snoozed struct {
+		profile *profile
+		scheduler.Item
+	}
+
+	var items []snoozed
+	available := false
+	for _, p := range aw.profiles {
+		if p.stores.Scheduler == nil {
+			continue
+		}
+		available = true
+		for _, item := range p.stores.Scheduler.List() {
+			items = append(items, snoozed{p, item})
+		}
+	}
+	if !available {
+		dialog.ShowInformation("Snoozed", "延後提醒無法使用", aw.window)
+		return
+	}
+	selected := -1
+
+	list := widget.NewList(
+		func() int {
+			return len(items)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			item := items[id]
+			obj.(*widget.Label).SetText(fmt.Sprintf("%s %s#%s [%s] %s", item.DueAt.Format("01-02 15:04"), aw.profilePrefix(item.profile.name), item.Notification.ID, item.Notification.Project, item.Notification.Title))
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		selected = id
+	}
+
+	// remove 從清單中移除選取的項目
+	remove := func() {
+		items = append(items[:selected], items[selected+1:]...)
+		selected = -1
+		list.UnselectAll()
+		list.Refresh()
+	}
+
+	nowBtn := widget.NewButton("Deliver Now", func() {
+		if selected < 0 || selected >= len(items) {
+			return
+		}
+		item := items[selected]
+		if err := item.profile.stores.Scheduler.Snooze(item.Notification, item.Notifier, time.Now(), time.Now()); err != nil {
+			dialog.ShowError(err, aw.window)
+			return
+		}
+		remove()
+	})
+	cancelBtn := widget.NewButton("Cancel Snooze", func() {
+		if selected < 0 || selected >= len(items) {
+			return
+		}
+		item := items[selected]
+		if _, err := item.profile.stores.Scheduler.Cancel(item.Notification.ID); err != nil {
+			dialog.ShowError(err, aw.window)
+			return
+		}
+		remove()
+	})
+
+	content := container.NewBorder(nil, container.NewHBox(nowBtn, cancelBtn), nil, nil, list)
+	d := dialog.NewCustom("Snoozed", "Close", content, aw.window)
+	d.Resize(fyne.NewSize(550, 400))
+	d.Show()
+}
@@ -0,0 +1,147 @@
+package gui
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+
+	"windows-notification/internal/monitor"
+)
+
+// trayMenu 是系統匣圖示與選單中隨監控狀態變動的項目
+type trayMenu struct {
+	desk   desktop.App
+	menu   *fyne.Menu
+	status *fyne.MenuItem // 目前狀態，不可點擊
+	toggle *fyne.MenuItem // Start/Stop Monitoring
+	resume *fyne.MenuItem // 勿擾中才可點擊
+
+	mu   sync.Mutex
+	icon fyne.Resource // 目前顯示的圖示，相同時不重新設定
+}
+
+// setupTray 建立系統匣圖示與選單；平台不支援系統匣時不做任何事
+func (aw *AppWindow) setupTray() {
+	desk, ok := aw.app.(desktop.App)
+	if !ok {
+		if aw.logger != nil {
+			aw.logger.Warn("此平台不支援系統匣，將只顯示視窗")
+		}
+		return
+	}
+
+	t := &trayMenu{desk: desk}
+	t.status = fyne.NewMenuItem("", nil)
+	t.status.Disabled = true
+	t.toggle = fyne.NewMenuItem("Start Monitoring", func() {
+		if aw.isStarted() {
+			aw.stop()
+		} else {
+			aw.start()
+		}
+	})
+	t.resume = fyne.NewMenuItem("Resume", aw.resume)
+	quit := fyne.NewMenuItem("Quit", aw.app.Quit)
+	quit.IsQuit = true
+
+	t.menu = fyne.NewMenu("Windows Notification Monitor",
+		t.status,
+		fyne.NewMenuItemSeparator(),
+		t.toggle,
+		fyne.NewMenuItem("Pause 30m", func() { aw.pause(30) }),
+		fyne.NewMenuItem("Pause 1h", func() { aw.pause(60) }),
+		t.resume,
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Open Inbox", aw.openInbox),
+		fyne.NewMenuItemSeparator(),
+		quit,
+	)
+	desk.SetSystemTrayMenu(t.menu)
+	aw.tray = t
+	aw.refreshTray()
+
+	// 關閉視窗時只隱藏，監控在背景繼續，從系統匣選單結束程式
+	if aw.cfg.Tray.CloseToTray {
+		aw.window.SetCloseIntercept(func() {
+			aw.window.Hide()
+			if aw.logger != nil {
+				aw.logger.Info("視窗已隱藏到系統匣，可從系統匣選單結束程式")
+			}
+		})
+	}
+}
+
+// openInbox 顯示視窗並切換到收件匣
+func (aw *AppWindow) openInbox() {
+	aw.window.Show()
+	aw.window.RequestFocus()
+	aw.tabs.Select(aw.inboxTab)
+}
+
+// trayStatus 返回系統匣顯示的狀態與說明文字；離線優先於勿擾顯示
+func (aw *AppWindow) trayStatus() (trayState, string) {
+	if !aw.isStarted() {
+		return trayStopped, "Stopped"
+	}
+
+	var down []string
+	for _, p := range aw.profiles {
+		status := p.engine.Status()
+		if status.Running && status.Health == monitor.HealthDown {
+			down = append(down, p.name)
+		}
+	}
+	if len(down) > 0 {
+		if len(aw.profiles) == 1 {
+			return trayOffline, "Offline"
+		}
+		return trayOffline, "Offline: " + strings.Join(down, ", ")
+	}
+
+	now := time.Now()
+	quiet := aw.engine.Quiet()
+	if until := quiet.PausedUntil(now); !until.IsZero() {
+		return trayPaused, "Paused until " + until.Format("01-02 15:04")
+	}
+	if quiet.Active(now) {
+		return trayPaused, "Quiet hours"
+	}
+	return trayRunning, "Monitoring"
+}
+
+// refreshTray 依監控狀態與未讀數更新系統匣圖示與選單
+func (aw *AppWindow) refreshTray() {
+	t := aw.tray
+	if t == nil {
+		return
+	}
+
+	state, status := aw.trayStatus()
+	unread := aw.unread()
+	if unread > 0 {
+		status += fmt.Sprintf(" - %d unread", unread)
+	}
+	toggle := "Start Monitoring"
+	if aw.isStarted() {
+		toggle = "Stop Monitoring"
+	}
+	paused := !aw.engine.Quiet().PausedUntil(time.Now()).IsZero()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.status.Label != status || t.toggle.Label != toggle || t.resume.Disabled == paused {
+		t.status.Label = status
+		t.toggle.Label = toggle
+		t.resume.Disabled = !paused
+		t.menu.Refresh()
+	}
+	if icon := trayIcon(state, unread); icon != t.icon {
+		t.desk.SetSystemTrayIcon(icon)
+		t.icon = icon
+	}
+}
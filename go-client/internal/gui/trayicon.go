@@ -0,0 +1,139 @@
+package gui
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"sync"
+
+	"fyne.io/fyne/v2"
+)
+
+// trayState 是系統匣圖示顯示的監控狀態
+type trayState int
+
+const (
+	trayStopped trayState = iota // 未監控
+	trayRunning                  // 監控中
+	trayPaused                   // 勿擾中
+	trayOffline                  // 有設定檔無法連線
+)
+
+// trayIconSize 是系統匣圖示的邊長（像素），由系統縮放到實際大小
+const trayIconSize = 64
+
+var (
+	trayColors = map[trayState]color.NRGBA{
+		trayStopped: {0x9e, 0x9e, 0x9e, 0xff},
+		trayRunning: {0x2e, 0x7d, 0x32, 0xff},
+		trayPaused:  {0xf9, 0xa8, 0x25, 0xff},
+		trayOffline: {0xc6, 0x28, 0x28, 0xff},
+	}
+	white     = color.NRGBA{0xff, 0xff, 0xff, 0xff}
+	badgeRed  = color.NRGBA{0xd3, 0x2f, 0x2f, 0xff}
+	trayCache sync.Map // 依狀態與未讀數快取已繪製的圖示
+)
+
+// digitGlyphs 是 3x5 點陣的數字與加號，用於未讀數徽章
+var digitGlyphs = map[rune][5]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", "..#", "..#"},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'+': {"...", ".#.", "###", ".#.", "..."},
+}
+
+// trayIcon 返回指定狀態與未讀數的系統匣圖示，未讀超過 9 則顯示 9+
+func trayIcon(state trayState, unread int) fyne.Resource {
+	badge := ""
+	switch {
+	case unread > 9:
+		badge = "9+"
+	case unread > 0:
+		badge = strconv.Itoa(unread)
+	}
+
+	key := fmt.Sprintf("tray-%d-%s.png", state, badge)
+	if res, ok := trayCache.Load(key); ok {
+		return res.(fyne.Resource)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, trayIconSize, trayIconSize))
+	c := trayColors[state]
+	fillCircle(img, 32, 32, 28, c)
+
+	// 除了顏色外以圖形區分狀態，避免只靠顏色辨識
+	switch state {
+	case trayStopped:
+		fillCircle(img, 32, 32, 18, color.NRGBA{})
+	case trayPaused:
+		fillRect(img, 22, 18, 8, 28, white)
+		fillRect(img, 34, 18, 8, 28, white)
+	case trayOffline:
+		fillRect(img, 16, 28, 32, 8, white)
+	}
+
+	if badge != "" {
+		fillCircle(img, 46, 46, 18, white)
+		fillCircle(img, 46, 46, 16, badgeRed)
+		drawText(img, 46, 46, badge, 3, white)
+	}
+
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	res := fyne.NewStaticResource(key, buf.Bytes())
+	trayCache.Store(key, res)
+	return res
+}
+
+// fillCircle 以圓心與半徑填滿圓形，alpha 為 0 的顏色會挖空該區域
+func fillCircle(img *image.NRGBA, cx, cy, r int, c color.NRGBA) {
+	for y := cy - r; y <= cy+r; y++ {
+		for x := cx - r; x <= cx+r; x++ {
+			dx, dy := x-cx, y-cy
+			if dx*dx+dy*dy <= r*r && image.Pt(x, y).In(img.Rect) {
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+// fillRect 填滿左上角為 (x, y) 的矩形
+func fillRect(img *image.NRGBA, x, y, w, h int, c color.NRGBA) {
+	for py := y; py < y+h; py++ {
+		for px := x; px < x+w; px++ {
+			if image.Pt(px, py).In(img.Rect) {
+				img.SetNRGBA(px, py, c)
+			}
+		}
+	}
+}
+
+// drawText 以點陣字型將文字置中繪製在 (cx, cy)，scale 為每個點的像素數
+func drawText(img *image.NRGBA, cx, cy int, text string, scale int, c color.NRGBA) {
+	runes := []rune(text)
+	width := (len(runes)*4 - 1) * scale
+	x0, y0 := cx-width/2, cy-5*scale/2
+	for i, r := range runes {
+		glyph, ok := digitGlyphs[r]
+		if !ok {
+			continue
+		}
+		for row, line := range glyph {
+			for col, dot := range line {
+				if dot == '#' {
+					fillRect(img, x0+(i*4+col)*scale, y0+row*scale, scale, scale, c)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package gui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"windows-notification/internal/api"
+)
+
+// confirmMarkUnread 詢問是否將通知退回伺服器佇列，以及本機是否重新顯示
+func (aw *AppWindow) confirmMarkUnread(p *profile, notif api.Notification, done func()) {
+	showAgain := widget.NewCheck("Show again on this machine", nil)
+	content := container.NewVBox(
+		widget.NewLabel(fmt.Sprintf("將通知 #%s 在伺服器上改回未通知，讓其他客戶端重新處理。", notif.ID)),
+		showAgain,
+	)
+
+	dialog.ShowCustomConfirm("Mark Unread", "Mark Unread", "Cancel", content, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		go func() {
+			if err := p.engine.MarkUnread(notif, showAgain.Checked); err != nil {
+				dialog.ShowError(err, aw.window)
+			}
+			done()
+		}()
+	}, aw.window)
+}
@@ -1,43 +1,72 @@
 package gui
 
 import (
-	"context"
+	"errors"
 	"fmt"
+	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 
+	"windows-notification/internal/actions"
 	"windows-notification/internal/api"
 	"windows-notification/internal/config"
+	"windows-notification/internal/control"
+	"windows-notification/internal/history"
+	"windows-notification/internal/instance"
 	"windows-notification/internal/logger"
+	"windows-notification/internal/monitor"
 	"windows-notification/internal/notification"
+	"windows-notification/internal/quarantine"
+	"windows-notification/internal/render"
 )
 
-// AppWindow 代表應用程式視窗
+// AppWindow 代表應用程式視窗；每個設定檔由各自的 monitor.Engine 監控，視窗只負責控制與顯示
 type AppWindow struct {
-	app          fyne.App
-	window       fyne.Window
-	cfg          *config.Config
-	apiClient    *api.Client
-	notifier     *notification.Notifier
-	logger       *logger.Logger
-	isRunning    bool
-	cancelFunc   context.CancelFunc
-	mu           sync.Mutex
-	statusLabel  *widget.Label
-	historyList  *widget.List
-	history      []string
+	app           fyne.App
+	window        fyne.Window
+	cfg           *config.Config
+	notifier      *notification.Notifier
+	profiles      []*profile      // 第一個為 default 設定檔
+	engine        *monitor.Engine // default 設定檔的引擎，提供勿擾狀態與樣板預覽
+	actions       *actions.Server
+	control       *control.Server
+	tray          *trayMenu // 平台不支援系統匣時為 nil
+	configErr     error     // 載入時的欄位錯誤，修正前不儲存設定檔
+	noSave        bool      // 設定檔無法解碼，為避免以預設值覆寫而不儲存
+	logger        *logger.Logger
+	unsubscribes  []func()
+	mu            sync.Mutex
+	started       bool // 使用者已開始監控
+	statusLabel   *widget.Label
+	historyList   *widget.List
+	history       []string
 	domainEntry   *widget.Entry
 	projectEntry  *widget.Entry
 	intervalEntry *widget.Entry
+	domainError   *widget.Label
+	intervalError *widget.Label
 	debugCheck    *widget.Check
 	startBtn      *widget.Button
 	stopBtn       *widget.Button
+	pauseEntry    *widget.Entry
+	dndLabel      *widget.Label
+	queueLabel    *widget.Label
+	outboxLabel   *widget.Label
+	tabs          *container.AppTabs
+	inboxTab      *container.TabItem
+	inboxTable    *widget.Table
+	inboxDetail   *widget.Label
+	inboxRecords  []history.Record
+	inboxSelected string
+	inboxSyncing  bool
 }
 
 // NewAppWindow creates a new application window
@@ -45,15 +74,14 @@ func NewAppWindow() *AppWindow {
 	myApp := app.New()
 	win := myApp.NewWindow("Windows Notification Monitor")
 
-	// 載入設定
-	cfg, err := config.Load("config.json")
-	if err != nil {
+	// 載入設定；欄位無效時保留解碼後的設定，讓使用者修正後儲存，不以預設值覆寫其他設定
+	cfg, loadErr := config.Load("config.json")
+	var invalid config.ValidationError
+	switch {
+	case errors.As(loadErr, &invalid):
+	case loadErr != nil:
 		// 使用預設設定
-		cfg = &config.Config{
-			Domain:   "http://localhost:9204",
-			Project:  "",
-			Interval: 5,
-		}
+		cfg = config.Default()
 	}
 
 	// 創建 logger
@@ -79,13 +107,96 @@ func NewAppWindow() *AppWindow {
 		})
 	}
 
-	// Create API client with logger
-	aw.apiClient = api.NewClientWithLogger(cfg.Domain, aw.logger)
+	// 設定檔存在但無效時提示錯誤，避免默默改用預設設定
+	switch {
+	case invalid != nil:
+		aw.configErr = invalid
+		if aw.logger != nil {
+			aw.logger.Errorf("設定檔有無效的欄位，修正後才能儲存: %v", invalid)
+		}
+	case loadErr != nil && !os.IsNotExist(loadErr):
+		aw.noSave = true
+		if aw.logger != nil {
+			aw.logger.Errorf("載入設定失敗，使用預設設定，設定檔不會被覆寫: %v", loadErr)
+		}
+	}
 
-	aw.buildUI()
+	// 第一次執行時先以設定精靈建立設定檔，完成後才開始建立監控
+	if os.IsNotExist(loadErr) {
+		aw.showWizard(aw.open)
+		return aw
+	}
+	aw.open()
 	return aw
 }
 
+// open 依設定建立監控引擎與主畫面
+func (aw *AppWindow) open() {
+	cfg := aw.cfg
+
+	// 通知後端設定錯誤時退回預設後端
+	backends, err := notification.NewRegistry(aw.notifier, cfg.Notifiers, aw.logger)
+	if err == nil {
+		backends, err = backends.WithDefault(cfg.Notifier)
+	}
+	if err != nil {
+		if aw.logger != nil {
+			aw.logger.Errorf("通知後端設定無效，僅使用預設後端: %v", err)
+		}
+		backends, _ = notification.NewRegistry(aw.notifier, nil, aw.logger)
+	}
+
+	// 通知動作按鈕服務在引擎建立前啟動，按鈕事件轉交給通知所屬設定檔的引擎
+	aw.actions, err = actions.Start(aw.handleAction, aw.logger)
+	if err != nil {
+		if aw.logger != nil {
+			aw.logger.Errorf("%v，通知將不顯示動作按鈕", err)
+		}
+	}
+
+	aw.openProfiles(backends)
+	aw.engine = aw.profiles[0].engine
+
+	aw.buildUI()
+	aw.showConfigErrors(aw.configErr)
+	aw.setupTray()
+	aw.startControl()
+
+	for _, p := range aw.profiles {
+		events, unsubscribe := p.engine.Subscribe()
+		aw.unsubscribes = append(aw.unsubscribes, unsubscribe)
+		go aw.watch(events)
+	}
+}
+
+// newClient 依設定檔的網域與金鑰建立 API 客戶端
+func (aw *AppWindow) newClient(cfg *config.Config) *api.Client {
+	client := api.NewClientWithLogger(cfg.Domain, aw.logger)
+	client.APIKey = cfg.APIKey
+	return client
+}
+
+// watch 依引擎事件更新畫面
+func (aw *AppWindow) watch(events <-chan monitor.Event) {
+	for ev := range events {
+		switch ev.Type {
+		case monitor.EventFetched:
+			aw.refreshDNDLabel()
+			aw.refreshQueueLabel()
+			aw.refreshOutboxLabel()
+		case monitor.EventHealthChanged:
+			aw.refreshStatusLabel()
+		case monitor.EventDisplayed, monitor.EventAcked, monitor.EventUpdated:
+			aw.refreshQueueLabel()
+			aw.refreshOutboxLabel()
+			aw.refreshInbox()
+		case monitor.EventError:
+			aw.refreshOutboxLabel()
+		}
+		aw.refreshTray()
+	}
+}
+
 // buildUI 建立使用者介面
 func (aw *AppWindow) buildUI() {
 	// Settings area
@@ -101,16 +212,35 @@ func (aw *AppWindow) buildUI() {
 	aw.intervalEntry.SetText(strconv.Itoa(aw.cfg.Interval))
 	aw.intervalEntry.SetPlaceHolder("Interval (seconds)")
 
+	// 輸入時即在欄位下方顯示錯誤，儲存時再以完整設定檢查一次
+	aw.domainError = newFieldError()
+	aw.domainEntry.Validator = func(s string) error {
+		return config.ValidateDomain(strings.TrimSpace(s))
+	}
+	aw.domainEntry.SetOnValidationChanged(func(err error) {
+		setFieldError(aw.domainError, err)
+	})
+	aw.intervalError = newFieldError()
+	aw.intervalEntry.Validator = func(s string) error {
+		_, err := parseInterval(s)
+		return err
+	}
+	aw.intervalEntry.SetOnValidationChanged(func(err error) {
+		setFieldError(aw.intervalError, err)
+	})
+
 	aw.debugCheck = widget.NewCheck("Debug Mode", func(checked bool) {
 		aw.cfg.Debug = checked
-		
+
 		// 更新 logger 的 debug 模式
 		if aw.logger != nil {
 			aw.logger.SetDebugMode(checked)
 		}
 
 		// 更新 API client
-		aw.apiClient = api.NewClientWithLogger(aw.cfg.Domain, aw.logger)
+		for _, p := range aw.profiles {
+			p.engine.SetSource(aw.newClient(p.cfg))
+		}
 
 		// Immediate feedback
 		if checked {
@@ -133,10 +263,12 @@ func (aw *AppWindow) buildUI() {
 	settingsForm := container.NewVBox(
 		widget.NewLabel("API Domain:"),
 		aw.domainEntry,
+		aw.domainError,
 		widget.NewLabel("Project Name:"),
 		aw.projectEntry,
 		widget.NewLabel("Check Interval (seconds):"),
 		aw.intervalEntry,
+		aw.intervalError,
 		aw.debugCheck,
 	)
 
@@ -160,10 +292,74 @@ func (aw *AppWindow) buildUI() {
 	})
 	aw.stopBtn.Disable()
 
-	controlBox := container.NewHBox(aw.startBtn, aw.stopBtn, testBtn, saveBtn)
+	// Rules dry run button
+	dryRunBtn := widget.NewButton("Rules Dry Run", func() {
+		go aw.dryRunRules()
+	})
+
+	// Template preview button
+	previewBtn := widget.NewButton("Preview", func() {
+		aw.previewTemplate()
+	})
+
+	// Quarantine button
+	quarantineBtn := widget.NewButton("Quarantine", func() {
+		aw.showQuarantine()
+	})
+
+	// Unacknowledged alerts button
+	unackedBtn := widget.NewButton("Unacknowledged", func() {
+		aw.showUnacknowledged()
+	})
+
+	// Snoozed notifications button
+	snoozedBtn := widget.NewButton("Snoozed", func() {
+		aw.showSnoozed()
+	})
+
+	// History search button
+	searchBtn := widget.NewButton("Search", func() {
+		aw.showSearch()
+	})
+
+	controlBox := container.NewHBox(aw.startBtn, aw.stopBtn, testBtn, saveBtn, dryRunBtn, previewBtn, quarantineBtn, unackedBtn, snoozedBtn, searchBtn)
+
+	// Do Not Disturb controls
+	aw.pauseEntry = widget.NewEntry()
+	aw.pauseEntry.SetText("30")
+	aw.pauseEntry.SetPlaceHolder("Minutes")
+
+	pauseBtn := widget.NewButton("Pause", func() {
+		aw.pauseFor()
+	})
+	tomorrowBtn := widget.NewButton("Until Tomorrow", func() {
+		aw.pause(0)
+	})
+	resumeBtn := widget.NewButton("Resume", func() {
+		aw.resume()
+	})
+
+	aw.dndLabel = widget.NewLabel("")
+	aw.refreshDNDLabel()
+
+	dndBox := container.NewVBox(
+		widget.NewLabel("Do Not Disturb (minutes):"),
+		container.NewBorder(nil, nil, nil, container.NewHBox(pauseBtn, tomorrowBtn, resumeBtn), aw.pauseEntry),
+		aw.dndLabel,
+	)
 
 	// Status label
 	aw.statusLabel = widget.NewLabel("Status: Not Started")
+	aw.queueLabel = widget.NewLabel("")
+	aw.refreshQueueLabel()
+	aw.outboxLabel = widget.NewLabel("")
+	aw.refreshOutboxLabel()
+
+	flushBtn := widget.NewButton("Flush Now", func() {
+		for _, p := range aw.profiles {
+			go p.engine.FlushOutbox(true)
+		}
+	})
 
 	// Notification history list
 	aw.historyList = widget.NewList(
@@ -179,51 +375,146 @@ func (aw *AppWindow) buildUI() {
 		},
 	)
 
-	historyBox := container.NewVBox(
-		widget.NewLabel("Notification History:"),
-		aw.historyList,
+	// Inbox and log tabs
+	aw.inboxTab = container.NewTabItem("Inbox", aw.buildInbox())
+	aw.tabs = container.NewAppTabs(
+		aw.inboxTab,
+		container.NewTabItem("Log", aw.historyList),
 	)
+	aw.refreshInboxTitle()
 
 	// Combine all elements
-	content := container.NewVBox(
+	top := container.NewVBox(
 		widget.NewLabel("Settings"),
 		settingsForm,
 		controlBox,
-		aw.statusLabel,
-		historyBox,
+		aw.buildProfiles(),
+		dndBox,
+		container.NewHBox(aw.statusLabel, aw.queueLabel, aw.outboxLabel, flushBtn),
 	)
+	content := container.NewBorder(top, nil, nil, nil, aw.tabs)
 
 	aw.window.SetContent(content)
-	aw.window.Resize(fyne.NewSize(600, 500))
+	aw.window.Resize(fyne.NewSize(800, 750))
 }
 
-// saveConfig saves configuration
-func (aw *AppWindow) saveConfig() {
-	interval, err := strconv.Atoi(aw.intervalEntry.Text)
-	if err != nil {
-		interval = 5
-		if aw.logger != nil {
-			aw.logger.Warnf("無效的間隔時間，使用預設值: %d 秒", interval)
+// saveConfig saves configuration；設定無效時在欄位下方顯示錯誤且不儲存
+func (aw *AppWindow) saveConfig() error {
+	next := *aw.cfg
+	next.Domain = strings.TrimSpace(aw.domainEntry.Text)
+	next.Project = aw.projectEntry.Text
+	next.Debug = aw.debugCheck.Checked
+
+	var errs config.ValidationError
+	if interval, err := parseInterval(aw.intervalEntry.Text); err != nil {
+		errs = append(errs, config.FieldError{Field: "interval", Message: err.Error()})
+	} else {
+		next.Interval = interval
+	}
+	if err := next.Validate(); err != nil {
+		var verr config.ValidationError
+		if errors.As(err, &verr) {
+			for _, fe := range verr {
+				// 輸入的間隔無效時 next 仍是舊值，不重複回報
+				if fe.Field == "interval" && errs.Field("interval") != "" {
+					continue
+				}
+				errs = append(errs, fe)
+			}
+		} else {
+			errs = append(errs, config.FieldError{Field: "config", Message: err.Error()})
 		}
 	}
 
-	aw.cfg.Domain = aw.domainEntry.Text
-	aw.cfg.Project = aw.projectEntry.Text
-	aw.cfg.Interval = interval
-	aw.cfg.Debug = aw.debugCheck.Checked
-
-	if err := config.Save("config.json", aw.cfg); err != nil {
+	aw.showConfigErrors(errs)
+	if len(errs) > 0 {
 		if aw.logger != nil {
-			aw.logger.Errorf("儲存設定失敗: %v", err)
+			aw.logger.Errorf("設定未儲存: %v", errs)
 		}
-	} else {
+		return errs
+	}
+
+	// default 設定檔與視窗共用同一份設定，只更新內容不替換指標
+	if aw.noSave {
 		if aw.logger != nil {
-			aw.logger.Success("設定已儲存")
-			aw.logger.Infof("Domain: %s, Project: %s, Interval: %d 秒", aw.cfg.Domain, aw.cfg.Project, aw.cfg.Interval)
+			aw.logger.Errorf("儲存設定失敗: %v", errConfigNotLoaded)
 		}
-		// Update API client
-		aw.apiClient = api.NewClientWithLogger(aw.cfg.Domain, aw.logger)
+		return errConfigNotLoaded
+	}
+	*aw.cfg = next
+	if err := aw.writeConfig(); err != nil {
+		return err
 	}
+	aw.configErr = nil
+	if aw.logger != nil {
+		aw.logger.Success("設定已儲存")
+		aw.logger.Infof("Domain: %s, Project: %s, Interval: %d 秒", aw.cfg.Domain, aw.cfg.Project, aw.cfg.Interval)
+	}
+	// Update API client
+	aw.engine.SetSource(aw.newClient(aw.cfg))
+	return nil
+}
+
+// errConfigNotLoaded 代表設定檔無法解碼，目前使用的是預設設定
+var errConfigNotLoaded = errors.New("設定檔載入失敗，為避免覆寫其中的設定而不儲存，請先修正 config.json")
+
+// writeConfig 儲存設定檔；設定檔無法載入或設定無效時不覆寫
+func (aw *AppWindow) writeConfig() error {
+	err := aw.cfg.Validate()
+	if aw.noSave {
+		err = errConfigNotLoaded
+	}
+	if err == nil {
+		err = config.Save("config.json", aw.cfg)
+	}
+	if err != nil && aw.logger != nil {
+		aw.logger.Errorf("儲存設定失敗: %v", err)
+	}
+	return err
+}
+
+// showConfigErrors 在網域與間隔欄位下方顯示設定錯誤，err 為 nil 時清除
+func (aw *AppWindow) showConfigErrors(err error) {
+	var errs config.ValidationError
+	errors.As(err, &errs)
+	setFieldError(aw.domainError, fieldErr(errs, "domain"))
+	setFieldError(aw.intervalError, fieldErr(errs, "interval"))
+}
+
+// parseInterval 解析查詢間隔輸入並檢查範圍
+func parseInterval(s string) (int, error) {
+	interval, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("需為整數秒數")
+	}
+	return interval, config.ValidateInterval(interval)
+}
+
+// fieldErr 返回指定欄位的錯誤，沒有錯誤時返回 nil
+func fieldErr(errs config.ValidationError, field string) error {
+	if msg := errs.Field(field); msg != "" {
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// newFieldError 建立顯示在欄位下方的錯誤訊息，沒有錯誤時隱藏
+func newFieldError() *widget.Label {
+	label := widget.NewLabel("")
+	label.Importance = widget.DangerImportance
+	label.Wrapping = fyne.TextWrapWord
+	label.Hide()
+	return label
+}
+
+// setFieldError 顯示或隱藏欄位錯誤
+func setFieldError(label *widget.Label, err error) {
+	if err == nil {
+		label.Hide()
+		return
+	}
+	label.SetText(err.Error())
+	label.Show()
 }
 
 // testAPI tests API connection immediately
@@ -235,158 +526,353 @@ func (aw *AppWindow) testAPI() {
 	}
 
 	// Immediately check notifications once
-	go aw.checkNotifications()
+	for _, p := range aw.profiles {
+		if p.enabled() {
+			go p.engine.Check()
+		}
+	}
 }
 
-// start begins monitoring
-func (aw *AppWindow) start() {
+// start begins monitoring；介面按鈕與控制 API 共用
+func (aw *AppWindow) start() error {
 	// Add immediate debug log (before acquiring lock)
 	if aw.logger != nil {
 		aw.logger.Info("開始按鈕被點擊")
 	}
 
 	aw.mu.Lock()
-	if aw.isRunning {
-		aw.mu.Unlock()
+	started := aw.started
+	aw.started = true
+	aw.mu.Unlock()
+	if started {
 		if aw.logger != nil {
 			aw.logger.Warn("監控已在執行中，忽略重複啟動")
 		}
-		return
+		return errors.New("監控已在執行中")
 	}
 
-	aw.isRunning = true
-
-	ctx, cancel := context.WithCancel(context.Background())
-	aw.cancelFunc = cancel
-	aw.mu.Unlock() // Release lock before UI updates
+	// 每個啟用的設定檔各自查詢，連線狀態與重試間隔互不影響
+	enabled := 0
+	for _, p := range aw.profiles {
+		if p.enabled() {
+			p.engine.Start()
+			enabled++
+		}
+	}
+	if enabled == 0 && aw.logger != nil {
+		aw.logger.Warn("沒有啟用的設定檔，啟用設定檔後會自動開始監控")
+	}
 
 	// Update UI on main thread
 	aw.startBtn.Disable()
 	aw.stopBtn.Enable()
-	aw.statusLabel.SetText("Status: Monitoring...")
+	aw.refreshStatusLabel()
 
-	// Log start information
-	if aw.logger != nil {
-		aw.logger.Infof("監控已啟動 - 專案: %s, 間隔: %d 秒", aw.cfg.Project, aw.cfg.Interval)
-		aw.logger.Infof("API 端點: %s", aw.cfg.Domain)
-		if aw.cfg.Debug {
-			aw.logger.Debug("Debug 模式已開啟 - 將顯示詳細的 API 資訊")
-		}
+	if aw.cfg.Debug && aw.logger != nil {
+		aw.logger.Debug("Debug 模式已開啟 - 將顯示詳細的 API 資訊")
 	}
-
-	go aw.monitorLoop(ctx)
+	return nil
 }
 
-// stop stops monitoring
-func (aw *AppWindow) stop() {
+// stop stops monitoring；介面按鈕與控制 API 共用
+func (aw *AppWindow) stop() error {
 	if aw.logger != nil {
 		aw.logger.Info("停止按鈕被點擊")
 	}
 
 	aw.mu.Lock()
-	if !aw.isRunning {
-		aw.mu.Unlock()
+	started := aw.started
+	aw.started = false
+	aw.mu.Unlock()
+	if !started {
 		if aw.logger != nil {
 			aw.logger.Warn("監控未在執行中，忽略停止操作")
 		}
-		return
+		return errors.New("監控未在執行中")
 	}
 
-	// Save cancel function and update state
-	cancelFunc := aw.cancelFunc
-	aw.isRunning = false
-	aw.mu.Unlock() // Release lock before UI updates
-
 	// Update UI on main thread
 	aw.startBtn.Enable()
 	aw.stopBtn.Disable()
 	aw.statusLabel.SetText("Status: Stopped")
+	aw.refreshTray()
+
+	// 停止時會等待進行中的查詢結束，不在 UI 執行緒上等待
+	if aw.logger != nil {
+		aw.logger.Info("正在取消監控迴圈...")
+	}
+	for _, p := range aw.profiles {
+		go p.engine.Stop()
+	}
+	return nil
+}
 
-	// Cancel outside of lock
-	if cancelFunc != nil {
+// refreshStatusLabel 依各設定檔的引擎狀態更新監控狀態顯示
+func (aw *AppWindow) refreshStatusLabel() {
+	defer aw.refreshTray()
+	if !aw.isStarted() {
+		return
+	}
+
+	running := 0
+	var down []string
+	for _, p := range aw.profiles {
+		status := p.engine.Status()
+		if !status.Running {
+			continue
+		}
+		running++
+		if status.Health == monitor.HealthDown {
+			down = append(down, p.name)
+		}
+	}
+
+	text := "Status: Monitoring..."
+	switch {
+	case running == 0:
+		text += " (no enabled profiles)"
+	case len(down) > 0 && len(aw.profiles) == 1:
+		text += " (API unreachable)"
+	case len(down) > 0:
+		text += " (API unreachable: " + strings.Join(down, ", ") + ")"
+	}
+	aw.statusLabel.SetText(text)
+}
+
+// pauseFor 依輸入的分鐘數手動開啟勿擾
+func (aw *AppWindow) pauseFor() {
+	minutes, err := strconv.Atoi(aw.pauseEntry.Text)
+	if err != nil || minutes <= 0 {
 		if aw.logger != nil {
-			aw.logger.Info("正在取消監控迴圈...")
+			aw.logger.Warnf("無效的勿擾分鐘數: %s", aw.pauseEntry.Text)
 		}
-		cancelFunc()
+		return
 	}
 
+	aw.pause(minutes)
+}
+
+// pause 手動開啟勿擾 minutes 分鐘，0 代表到明天早上；介面按鈕與控制 API 共用
+func (aw *AppWindow) pause(minutes int) time.Time {
+	// 勿擾套用到所有設定檔
+	now := time.Now()
+	var until time.Time
+	for _, p := range aw.profiles {
+		if minutes > 0 {
+			until = p.engine.Quiet().PauseFor(now, time.Duration(minutes)*time.Minute)
+		} else {
+			until = p.engine.Quiet().PauseUntilTomorrow(now)
+		}
+	}
 	if aw.logger != nil {
-		aw.logger.Success("監控已成功停止")
+		if minutes > 0 {
+			aw.logger.Infof("勿擾已開啟 %d 分鐘，直到 %s", minutes, until.Format("15:04"))
+		} else {
+			aw.logger.Infof("勿擾已開啟，直到 %s", until.Format("01-02 15:04"))
+		}
 	}
+	aw.refreshDNDLabel()
+	return until
 }
 
-// monitorLoop 監控迴圈
-func (aw *AppWindow) monitorLoop(ctx context.Context) {
+// resume 取消手動勿擾
+func (aw *AppWindow) resume() {
+	for _, p := range aw.profiles {
+		p.engine.Quiet().Resume()
+	}
 	if aw.logger != nil {
-		aw.logger.Debug("監控迴圈已啟動")
+		aw.logger.Info("已取消手動勿擾")
 	}
+	aw.refreshDNDLabel()
+}
 
-	ticker := time.NewTicker(time.Duration(aw.cfg.Interval) * time.Second)
-	defer ticker.Stop()
-
-	// 立即執行一次
-	if aw.logger != nil {
-		aw.logger.Debug("執行首次通知檢查...")
+// refreshDNDLabel 更新勿擾狀態顯示
+func (aw *AppWindow) refreshDNDLabel() {
+	now := time.Now()
+	quiet := aw.engine.Quiet()
+	text := "DND: Off"
+	if until := quiet.PausedUntil(now); !until.IsZero() {
+		text = fmt.Sprintf("DND: Paused until %s", until.Format("01-02 15:04"))
+	} else if quiet.Active(now) {
+		text = "DND: Quiet hours"
+	}
+	held := 0
+	for _, p := range aw.profiles {
+		held += p.engine.Quiet().HeldCount()
 	}
-	aw.checkNotifications()
+	if held > 0 {
+		text += fmt.Sprintf(" (%d held)", held)
+	}
+	aw.dndLabel.SetText(text)
+	aw.refreshTray()
+}
 
-	for {
-		select {
-		case <-ctx.Done():
+// dryRunRules 查詢目前未通知的記錄，顯示每一則命中的規則但不執行任何動作
+func (aw *AppWindow) dryRunRules() {
+	var lines []string
+	for _, p := range aw.profiles {
+		if !p.enabled() {
+			continue
+		}
+		results, err := p.engine.DryRun()
+		if err != nil {
 			if aw.logger != nil {
-				aw.logger.Debug("監控迴圈收到取消信號，正在退出...")
+				aw.logger.Errorf("API 查詢失敗: %v", err)
 			}
+			dialog.ShowError(err, aw.window)
 			return
-		case <-ticker.C:
-			if aw.logger != nil {
-				aw.logger.Debug("定時器觸發，執行通知檢查...")
-			}
-			aw.checkNotifications()
+		}
+
+		prefix := aw.profilePrefix(p.name)
+		for _, r := range results {
+			notif := r.Notification
+			lines = append(lines, fmt.Sprintf("%s#%s [%s] %s\n    %s", prefix, notif.ID, notif.Project, notif.Title, r.Decision.Describe()))
 		}
 	}
+	if len(lines) == 0 {
+		lines = append(lines, "沒有未通知的記錄")
+	}
+
+	content := container.NewVScroll(widget.NewLabel(strings.Join(lines, "\n")))
+	content.SetMinSize(fyne.NewSize(500, 300))
+	dialog.ShowCustom("Rules Dry Run", "Close", content, aw.window)
 }
 
-// checkNotifications checks for new notifications
-func (aw *AppWindow) checkNotifications() {
-	notifications, err := aw.apiClient.GetUnnotifiedNotifications(aw.cfg.Project)
+// previewTemplate 以範例通知預覽目前專案的樣板
+func (aw *AppWindow) previewTemplate() {
+	sample := render.Sample(aw.projectEntry.Text)
+	title, message, err := aw.engine.Templates().Render(sample)
 	if err != nil {
-		if aw.logger != nil {
-			aw.logger.Errorf("API 查詢失敗: %v", err)
-		}
+		dialog.ShowError(err, aw.window)
 		return
 	}
 
-	if len(notifications) == 0 {
-		if aw.logger != nil {
-			aw.logger.Debug("沒有未通知的記錄")
+	titleLabel := widget.NewLabelWithStyle(title, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	messageLabel := widget.NewLabel(message)
+	messageLabel.Wrapping = fyne.TextWrapWord
+
+	content := container.NewVBox(titleLabel, messageLabel)
+	showBtn := widget.NewButton("Show Toast", func() {
+		if err := aw.notifier.Show(title, message); err != nil {
+			dialog.ShowError(err, aw.window)
+		}
+	})
+
+	d := dialog.NewCustom("Template Preview", "Close", container.NewBorder(nil, showBtn, nil, nil, content), aw.window)
+	d.Resize(fyne.NewSize(450, 250))
+	d.Show()
+}
+
+// refreshOutboxLabel 更新待送出的狀態更新數量與最舊一筆的等待時間
+func (aw *AppWindow) refreshOutboxLabel() {
+	total := 0
+	var oldest time.Time
+	for _, p := range aw.profiles {
+		status := p.engine.Status()
+		total += status.Outbox
+		if status.Outbox > 0 && (oldest.IsZero() || status.OutboxOldest.Before(oldest)) {
+			oldest = status.OutboxOldest
 		}
+	}
+	if total == 0 {
+		aw.outboxLabel.SetText("Outbox: 0")
 		return
 	}
+	age := time.Since(oldest).Round(time.Second)
+	aw.outboxLabel.SetText(fmt.Sprintf("Outbox: %d (oldest %s)", total, age))
+}
 
-	if aw.logger != nil {
-		aw.logger.Infof("發現 %d 個未通知的記錄", len(notifications))
+// refreshQueueLabel 更新等待顯示的佇列長度
+func (aw *AppWindow) refreshQueueLabel() {
+	if !aw.cfg.RateLimit.Enabled {
+		aw.queueLabel.SetText("")
+		return
 	}
+	queued := 0
+	for _, p := range aw.profiles {
+		queued += p.engine.Status().Queued
+	}
+	aw.queueLabel.SetText(fmt.Sprintf("Queue: %d", queued))
+}
 
-	for _, notif := range notifications {
-		// Show system notification
-		if err := aw.notifier.Show(notif.Title, notif.Message); err != nil {
-			if aw.logger != nil {
-				aw.logger.Errorf("顯示通知失敗 (ID: %s): %v", notif.ID, err)
-			}
+// showQuarantine 顯示隔離清單，可重試或捨棄
+func (aw *AppWindow) showQuarantine() {
+	type quarantined struct {
+		profile *profile
+		quarantine.Item
+	}
+
+	var items []quarantined
+	available := false
+	for _, p := range aw.profiles {
+		if p.stores.Quarantine == nil {
 			continue
 		}
+		available = true
+		for _, item := range p.stores.Quarantine.List() {
+			items = append(items, quarantined{p, item})
+		}
+	}
+	if !available {
+		dialog.ShowInformation("Quarantine", "隔離清單無法使用", aw.window)
+		return
+	}
+	selected := -1
 
-		// Update status to notified
-		if err := aw.apiClient.UpdateNotificationStatus(notif.ID); err != nil {
-			if aw.logger != nil {
-				aw.logger.Errorf("更新狀態失敗 (ID: %s): %v", notif.ID, err)
-			}
-		} else {
-			if aw.logger != nil {
-				aw.logger.Successf("已通知: %s - %s", notif.Title, notif.Message)
-			}
+	detail := widget.NewLabel("")
+	detail.Wrapping = fyne.TextWrapWord
+
+	list := widget.NewList(
+		func() int {
+			return len(items)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			item := items[id]
+			obj.(*widget.Label).SetText(fmt.Sprintf("%s#%s [%s] %s (x%d)", aw.profilePrefix(item.profile.name), item.Notification.ID, item.Notification.Project, item.Notification.Title, item.Failures))
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		selected = id
+		item := items[id]
+		detail.SetText(fmt.Sprintf("最後失敗: %s\n錯誤: %s", item.LastFailedAt.Format("2006-01-02 15:04:05"), item.LastError))
+	}
+
+	// release 將選取的通知移出隔離清單
+	release := func() (quarantined, bool) {
+		if selected < 0 || selected >= len(items) {
+			return quarantined{}, false
+		}
+		item := items[selected]
+		if _, err := item.profile.stores.Quarantine.Release(item.Notification.ID); err != nil && aw.logger != nil {
+			aw.logger.Warnf("儲存隔離清單失敗: %v", err)
 		}
+		items = append(items[:selected], items[selected+1:]...)
+		selected = -1
+		list.UnselectAll()
+		list.Refresh()
+		detail.SetText("")
+		return item, true
 	}
+
+	retryBtn := widget.NewButton("Retry", func() {
+		if item, ok := release(); ok && aw.logger != nil {
+			aw.logger.Infof("已將通知移出隔離，下次查詢時重試 (ID: %s)", item.Notification.ID)
+		}
+	})
+	discardBtn := widget.NewButton("Discard", func() {
+		if item, ok := release(); ok {
+			// 捨棄時直接更新為已通知，避免伺服器持續回傳
+			go item.profile.engine.MarkNotified(item.Notification, "已捨棄隔離通知")
+		}
+	})
+
+	content := container.NewBorder(nil, container.NewVBox(detail, container.NewHBox(retryBtn, discardBtn)), nil, nil, list)
+	d := dialog.NewCustom("Quarantine", "Close", content, aw.window)
+	d.Resize(fyne.NewSize(550, 400))
+	d.Show()
 }
 
 // addHistory 新增歷史記錄（已棄用，由 logger 回調）
@@ -405,7 +891,10 @@ func (aw *AppWindow) addHistoryDirect(msg string) {
 	}
 	aw.mu.Unlock()
 
-	aw.historyList.Refresh()
+	// 設定精靈期間主畫面尚未建立
+	if aw.historyList != nil {
+		aw.historyList.Refresh()
+	}
 }
 
 // Run 執行應用程式
@@ -413,12 +902,45 @@ func (aw *AppWindow) Run() {
 	if aw.logger != nil {
 		aw.logger.Info("應用程式視窗已開啟")
 	}
-	
-	aw.window.ShowAndRun()
-	
+
+	// 只有系統匣可用時才能隱藏視窗啟動，否則將無法開啟視窗
+	if aw.tray != nil && aw.cfg.Tray.StartMinimized {
+		if aw.logger != nil {
+			aw.logger.Info("啟動時隱藏視窗，可從系統匣開啟")
+		}
+		aw.app.Run()
+	} else {
+		aw.window.ShowAndRun()
+	}
+
 	// 清理資源
+	for _, unsubscribe := range aw.unsubscribes {
+		unsubscribe()
+	}
+	for _, p := range aw.profiles {
+		p.engine.Close()
+	}
+	if aw.control != nil {
+		aw.control.Close()
+	}
+	if aw.actions != nil {
+		aw.actions.Close()
+	}
 	if aw.logger != nil {
 		aw.logger.Info("應用程式即將關閉")
 		aw.logger.Close()
 	}
 }
+
+// HandleForward 處理重複啟動時轉交的命令，以及命令列轉交的 ack 與 unread
+func (aw *AppWindow) HandleForward(args []string) (string, error) {
+	if len(args) > 0 && args[0] != instance.Show {
+		return instance.Dispatch(forwardCommands{aw}, args)
+	}
+	if aw.logger != nil {
+		aw.logger.Info("收到重複啟動，顯示視窗")
+	}
+	aw.window.Show()
+	aw.window.RequestFocus()
+	return "", nil
+}
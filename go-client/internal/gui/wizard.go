@@ -0,0 +1,508 @@
+package gui
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+
+	"windows-notification/internal/api"
+	"windows-notification/internal/config"
+	"windows-notification/internal/notification"
+)
+
+// discoverLimit 是探索專案時查詢的最近通知數
+const discoverLimit = 500
+
+// 設定精靈的通知後端選項
+const (
+	wizardToast   = "Windows notification"
+	wizardWebhook = "Webhook"
+)
+
+// wizardWebhookName 是精靈建立的 webhook 後端在 notifiers 中的名稱
+const wizardWebhookName = "webhook"
+
+// wizard 是第一次執行時的設定精靈，依序設定伺服器、專案與通知後端，完成時才儲存設定
+type wizard struct {
+	aw   *AppWindow
+	cfg  *config.Config // 編輯中的設定
+	done func()         // 儲存後呼叫
+
+	steps     []fyne.CanvasObject
+	titles    []string
+	step      int
+	body      *fyne.Container
+	stepLabel *widget.Label
+	backBtn   *widget.Button
+	nextBtn   *widget.Button
+
+	domainEntry *widget.Entry
+	keyEntry    *widget.Entry
+	testBtn     *widget.Button
+	diagLabel   *widget.Label
+	connected   bool // 目前的網址與金鑰已連線成功
+
+	projectCheck *widget.CheckGroup
+	projectHint  *widget.Label
+	otherProject *widget.Entry
+
+	notifierRadio *widget.RadioGroup
+	webhookEntry  *widget.Entry
+	toastLabel    *widget.Label
+
+	summaryLabel *widget.Label
+}
+
+// showWizard 在視窗中顯示設定精靈，儲存設定後呼叫 done
+func (aw *AppWindow) showWizard(done func()) {
+	if aw.logger != nil {
+		aw.logger.Info("找不到設定檔，開啟設定精靈")
+	}
+
+	w := &wizard{aw: aw, cfg: config.Default(), done: done}
+	w.steps = []fyne.CanvasObject{w.buildServer(), w.buildProjects(), w.buildNotifier(), w.buildFinish()}
+	w.titles = []string{"Server", "Projects", "Notifications", "Finish"}
+
+	w.stepLabel = widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	w.body = container.NewStack()
+	w.backBtn = widget.NewButton("Back", func() { w.show(w.step - 1) })
+	w.nextBtn = widget.NewButton("Next", w.forward)
+	w.nextBtn.Importance = widget.HighImportance
+
+	top := container.NewVBox(widget.NewLabel("Welcome! Let's connect this client to your notification server."), w.stepLabel)
+	bottom := container.NewHBox(layout.NewSpacer(), w.backBtn, w.nextBtn)
+	aw.window.SetContent(container.NewBorder(top, bottom, nil, nil, w.body))
+	aw.window.Resize(fyne.NewSize(640, 480))
+	w.show(0)
+}
+
+// show 切換到指定步驟
+func (w *wizard) show(step int) {
+	w.step = step
+	w.stepLabel.SetText(fmt.Sprintf("Step %d of %d: %s", step+1, len(w.steps), w.titles[step]))
+	w.body.Objects = []fyne.CanvasObject{w.steps[step]}
+	w.body.Refresh()
+
+	if step == 0 {
+		w.backBtn.Disable()
+	} else {
+		w.backBtn.Enable()
+	}
+	if step == len(w.steps)-1 {
+		w.summaryLabel.SetText(w.summary())
+		w.nextBtn.SetText("Save")
+	} else {
+		w.nextBtn.SetText("Next")
+	}
+}
+
+// forward 檢查目前步驟後前往下一步，最後一步時儲存設定
+func (w *wizard) forward() {
+	switch w.step {
+	case 0:
+		if _, err := serverURL(w.domainEntry.Text); err != nil {
+			w.diagLabel.SetText(err.Error())
+			return
+		}
+		if !w.connected {
+			dialog.ShowConfirm("Connection not verified",
+				"The connection to this server has not been tested successfully. Continue anyway?",
+				func(ok bool) {
+					if ok {
+						w.show(1)
+					}
+				}, w.aw.window)
+			return
+		}
+	case 2:
+		if w.notifierRadio.Selected == wizardWebhook {
+			if _, err := serverURL(w.webhookEntry.Text); err != nil {
+				w.toastLabel.SetText("Webhook " + err.Error())
+				return
+			}
+		}
+	case len(w.steps) - 1:
+		w.save()
+		return
+	}
+	w.show(w.step + 1)
+}
+
+// buildServer 建立伺服器網址、金鑰與連線測試步驟
+func (w *wizard) buildServer() fyne.CanvasObject {
+	w.domainEntry = widget.NewEntry()
+	w.domainEntry.SetText(w.cfg.Domain)
+	w.domainEntry.SetPlaceHolder("e.g. https://notify.example.com")
+	w.keyEntry = widget.NewPasswordEntry()
+	w.keyEntry.SetPlaceHolder("Leave empty if the server does not require a key")
+
+	// 網址或金鑰變更後需要重新測試
+	reset := func(string) {
+		w.connected = false
+	}
+	w.domainEntry.OnChanged = reset
+	w.keyEntry.OnChanged = reset
+
+	w.diagLabel = widget.NewLabel("Enter the server URL and test the connection.")
+	w.diagLabel.Wrapping = fyne.TextWrapWord
+	w.testBtn = widget.NewButton("Test Connection", w.testConnection)
+
+	form := widget.NewForm(
+		widget.NewFormItem("Server URL", w.domainEntry),
+		widget.NewFormItem("API Key", w.keyEntry),
+	)
+	return container.NewVBox(form, container.NewHBox(w.testBtn), w.diagLabel)
+}
+
+// testConnection 查詢最近的通知以確認連線，並從中找出可選擇的專案
+func (w *wizard) testConnection() {
+	domain, err := serverURL(w.domainEntry.Text)
+	if err != nil {
+		w.diagLabel.SetText(err.Error())
+		return
+	}
+	key := strings.TrimSpace(w.keyEntry.Text)
+
+	w.testBtn.Disable()
+	w.diagLabel.SetText("Connecting to " + domain + " ...")
+	go func() {
+		defer w.testBtn.Enable()
+
+		client := api.NewClientWithLogger(domain, w.aw.logger)
+		client.APIKey = key
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+
+		start := time.Now()
+		notifs, err := client.ListNotifications(ctx, api.ListOptions{Status: api.StatusAny, Limit: discoverLimit})
+		elapsed := time.Since(start).Milliseconds()
+		if err != nil {
+			w.connected = false
+			w.diagLabel.SetText(diagnose(domain, err))
+			if w.aw.logger != nil {
+				w.aw.logger.Warnf("設定精靈連線測試失敗: %v", err)
+			}
+			return
+		}
+
+		// 網址或金鑰在測試期間被修改時，結果不代表目前的輸入
+		if d, _ := serverURL(w.domainEntry.Text); d != domain || strings.TrimSpace(w.keyEntry.Text) != key {
+			w.diagLabel.SetText("The URL or key changed during the test. Please test again.")
+			return
+		}
+
+		projects := discoverProjects(notifs)
+		w.connected = true
+		w.setProjects(projects)
+		w.diagLabel.SetText(fmt.Sprintf("Connected to %s in %d ms.\nFound %d recent notifications in %d projects.",
+			domain, elapsed, len(notifs), len(projects)))
+		if w.aw.logger != nil {
+			w.aw.logger.Successf("設定精靈連線成功: %s (%d 個專案)", domain, len(projects))
+		}
+	}()
+}
+
+// serverURL 檢查並整理使用者輸入的網址
+func serverURL(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", errors.New("URL is required")
+	}
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("URL must start with http:// or https://, for example http://localhost:9204 (got %q)", s)
+	}
+	return strings.TrimRight(s, "/"), nil
+}
+
+// diagnose 將連線錯誤轉為使用者看得懂的說明與建議
+func diagnose(domain string, err error) string {
+	var (
+		httpErr    *api.HTTPError
+		dnsErr     *net.DNSError
+		opErr      *net.OpError
+		netErr     net.Error
+		certErr    *tls.CertificateVerificationError
+		authErr    x509.UnknownAuthorityError
+		hostErr    x509.HostnameError
+		syntaxErr  *json.SyntaxError
+		typeErr    *json.UnmarshalTypeError
+		serverHost = domain
+	)
+	if u, err := url.Parse(domain); err == nil {
+		serverHost = u.Host
+	}
+
+	switch {
+	case errors.As(err, &httpErr):
+		switch httpErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return fmt.Sprintf("The server rejected the API key (HTTP %d). Check the key, or leave it empty if the server does not require one.", httpErr.StatusCode)
+		case http.StatusNotFound:
+			return "The server has no /api/notifications endpoint (HTTP 404). Check that the URL points to the notification server itself, without an extra path."
+		}
+		msg := fmt.Sprintf("The server returned HTTP %d.", httpErr.StatusCode)
+		if httpErr.Message != "" {
+			msg += " Message: " + httpErr.Message
+		}
+		return msg
+	case errors.As(err, &dnsErr):
+		return fmt.Sprintf("Cannot resolve host %q. Check the server name, and your network or VPN connection.", dnsErr.Name)
+	case errors.As(err, &certErr), errors.As(err, &authErr), errors.As(err, &hostErr):
+		return fmt.Sprintf("The server's TLS certificate is not trusted: %v", err)
+	case strings.Contains(err.Error(), "server gave HTTP response to HTTPS client"):
+		return "The server does not speak HTTPS on this port. Try http:// instead of https://."
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return fmt.Sprintf("No response from %s in time. The server may be down, or a firewall or proxy is blocking it.", serverHost)
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return fmt.Sprintf("Cannot connect to %s. Check that the server is running and the port is correct.", serverHost)
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return "The server responded, but not with the notification API format. Check that the URL points to the notification server."
+	}
+	return fmt.Sprintf("Connection failed: %v", err)
+}
+
+// discoverProjects 返回通知中出現過的專案，依名稱排序
+func discoverProjects(notifs []api.Notification) []string {
+	seen := make(map[string]bool)
+	var projects []string
+	for _, n := range notifs {
+		if n.Project != "" && !seen[n.Project] {
+			seen[n.Project] = true
+			projects = append(projects, n.Project)
+		}
+	}
+	sort.Strings(projects)
+	return projects
+}
+
+// buildProjects 建立專案選擇步驟
+func (w *wizard) buildProjects() fyne.CanvasObject {
+	w.projectCheck = widget.NewCheckGroup(nil, nil)
+	w.projectHint = widget.NewLabel("Test the connection on the previous step to list the server's projects.")
+	w.projectHint.Wrapping = fyne.TextWrapWord
+	w.otherProject = widget.NewEntry()
+	w.otherProject.SetPlaceHolder("A project not listed above (optional)")
+
+	top := container.NewVBox(
+		widget.NewLabel("Choose the projects to monitor. Leave everything empty to monitor all projects."),
+		w.projectHint,
+	)
+	bottom := widget.NewForm(widget.NewFormItem("Other project", w.otherProject))
+	return container.NewBorder(top, bottom, nil, nil, container.NewVScroll(w.projectCheck))
+}
+
+// setProjects 更新可選擇的專案，保留原本的勾選
+func (w *wizard) setProjects(projects []string) {
+	w.projectCheck.Options = projects
+	w.projectCheck.Refresh()
+	if len(projects) == 0 {
+		w.projectHint.SetText("The server has no notifications yet, so no projects were found. Type a project name below, or leave it empty to monitor all projects.")
+	} else {
+		w.projectHint.SetText(fmt.Sprintf("%d projects found in recent notifications.", len(projects)))
+	}
+}
+
+// selectedProjects 返回勾選與手動輸入的專案，依清單順序且不重複
+func (w *wizard) selectedProjects() []string {
+	var projects []string
+	seen := make(map[string]bool)
+	for _, p := range append(append([]string{}, w.projectCheck.Selected...), strings.TrimSpace(w.otherProject.Text)) {
+		if p != "" && !seen[p] {
+			seen[p] = true
+			projects = append(projects, p)
+		}
+	}
+	return projects
+}
+
+// buildNotifier 建立通知後端選擇與測試通知步驟
+func (w *wizard) buildNotifier() fyne.CanvasObject {
+	w.webhookEntry = widget.NewEntry()
+	w.webhookEntry.SetPlaceHolder("e.g. https://hooks.example.com/notify")
+	w.webhookEntry.Disable()
+
+	w.notifierRadio = widget.NewRadioGroup([]string{wizardToast, wizardWebhook}, func(selected string) {
+		if selected == wizardWebhook {
+			w.webhookEntry.Enable()
+		} else {
+			w.webhookEntry.Disable()
+		}
+	})
+	w.notifierRadio.Required = true
+	w.notifierRadio.SetSelected(wizardToast)
+
+	w.toastLabel = widget.NewLabel("")
+	w.toastLabel.Wrapping = fyne.TextWrapWord
+	testBtn := widget.NewButton("Send Test Notification", w.sendTest)
+
+	return container.NewVBox(
+		widget.NewLabel("Choose how notifications are shown:"),
+		w.notifierRadio,
+		widget.NewForm(widget.NewFormItem("Webhook URL", w.webhookEntry)),
+		container.NewHBox(testBtn),
+		w.toastLabel,
+	)
+}
+
+// notifiers 返回選擇的預設通知後端名稱與需要加入的後端設定
+func (w *wizard) notifiers() (string, map[string]config.NotifierConfig) {
+	if w.notifierRadio.Selected != wizardWebhook {
+		return "", nil
+	}
+	hook, _ := serverURL(w.webhookEntry.Text)
+	return wizardWebhookName, map[string]config.NotifierConfig{
+		wizardWebhookName: {Type: "webhook", URL: hook},
+	}
+}
+
+// sendTest 以選擇的通知後端送出一則測試通知
+func (w *wizard) sendTest() {
+	if w.notifierRadio.Selected == wizardWebhook {
+		if _, err := serverURL(w.webhookEntry.Text); err != nil {
+			w.toastLabel.SetText("Webhook " + err.Error())
+			return
+		}
+	}
+
+	name, cfgs := w.notifiers()
+	registry, err := notification.NewRegistry(w.aw.notifier, cfgs, w.aw.logger)
+	if err != nil {
+		w.toastLabel.SetText(fmt.Sprintf("Invalid notifier: %v", err))
+		return
+	}
+	backend, err := registry.Get(name)
+	if err != nil {
+		w.toastLabel.SetText(fmt.Sprintf("Invalid notifier: %v", err))
+		return
+	}
+
+	w.toastLabel.SetText("Sending test notification...")
+	go func() {
+		err := backend.Show("Windows Notification Monitor", "This is a test notification. Notifications from your server will look like this.")
+		if err != nil {
+			w.toastLabel.SetText(fmt.Sprintf("Test notification failed: %v", err))
+			if w.aw.logger != nil {
+				w.aw.logger.Warnf("設定精靈測試通知失敗: %v", err)
+			}
+			return
+		}
+		w.toastLabel.SetText("Test notification sent. If you did not see it, check the notifier settings and try again.")
+	}()
+}
+
+// buildFinish 建立確認並儲存的最後一步
+func (w *wizard) buildFinish() fyne.CanvasObject {
+	w.summaryLabel = widget.NewLabel("")
+	w.summaryLabel.Wrapping = fyne.TextWrapWord
+	return container.NewVBox(
+		widget.NewLabel("Review the settings and press Save. You can change them later in config.json."),
+		w.summaryLabel,
+	)
+}
+
+// summary 返回即將儲存的設定摘要
+func (w *wizard) summary() string {
+	domain, _ := serverURL(w.domainEntry.Text)
+	key := "not set"
+	if strings.TrimSpace(w.keyEntry.Text) != "" {
+		key = "set"
+	}
+	projects := "all projects"
+	if selected := w.selectedProjects(); len(selected) > 0 {
+		projects = strings.Join(selected, ", ")
+	}
+	notifier := wizardToast
+	if w.notifierRadio.Selected == wizardWebhook {
+		notifier = "Webhook (" + strings.TrimSpace(w.webhookEntry.Text) + ")"
+	}
+	return fmt.Sprintf("Server: %s\nAPI key: %s\nProjects: %s\nNotifications: %s", domain, key, projects, notifier)
+}
+
+// apply 將精靈的選擇寫入設定；第一個專案為 default 設定檔，其餘各自成為同一伺服器的設定檔
+func (w *wizard) apply() {
+	cfg := w.cfg
+	cfg.Domain, _ = serverURL(w.domainEntry.Text)
+	cfg.APIKey = strings.TrimSpace(w.keyEntry.Text)
+	cfg.Notifier, cfg.Notifiers = w.notifiers()
+
+	projects := w.selectedProjects()
+	cfg.Project = ""
+	cfg.Profiles = nil
+	if len(projects) == 0 {
+		return
+	}
+	cfg.Project = projects[0]
+
+	used := map[string]bool{config.DefaultProfile: true}
+	for _, project := range projects[1:] {
+		name := profileName(project, used)
+		used[name] = true
+		cfg.Profiles = append(cfg.Profiles, config.Profile{
+			Name:    name,
+			Domain:  cfg.Domain,
+			APIKey:  cfg.APIKey,
+			Project: project,
+		})
+	}
+}
+
+// profileName 以專案名稱產生不重複且可作為目錄名稱的設定檔名稱
+func profileName(project string, used map[string]bool) string {
+	base := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '-'
+		}
+		return r
+	}, project)
+	if base == "." || base == ".." {
+		base = "project"
+	}
+
+	name := base
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	return name
+}
+
+// save 儲存設定後關閉精靈並開始建立主畫面
+func (w *wizard) save() {
+	w.apply()
+	if err := w.cfg.Validate(); err != nil {
+		if w.aw.logger != nil {
+			w.aw.logger.Errorf("設定未儲存: %v", err)
+		}
+		dialog.ShowError(err, w.aw.window)
+		return
+	}
+	if err := config.Save("config.json", w.cfg); err != nil {
+		if w.aw.logger != nil {
+			w.aw.logger.Errorf("儲存設定失敗: %v", err)
+		}
+		dialog.ShowError(fmt.Errorf("cannot save config.json: %w", err), w.aw.window)
+		return
+	}
+
+	w.aw.cfg = w.cfg
+	if w.aw.logger != nil {
+		w.aw.logger.Success("設定精靈已完成，設定已儲存")
+		w.aw.logger.Infof("Domain: %s, Project: %s, 設定檔: %d 個", w.cfg.Domain, w.cfg.Project, len(w.cfg.Profiles)+1)
+	}
+	w.done()
+}
@@ -0,0 +1,413 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"windows-notification/internal/api"
+	"windows-notification/internal/config"
+	"windows-notification/internal/jsonfile"
+)
+
+// Outcome 代表通知在本機的處理結果
+type Outcome string
+
+const (
+	OutcomePending     Outcome = "pending"     // 已收到，尚未處理（勿擾保留、排隊或延後中）
+	OutcomeDisplayed   Outcome = "displayed"   // 已顯示
+	OutcomeSuppressed  Outcome = "suppressed"  // 規則略過
+	OutcomeSilenced    Outcome = "silenced"    // 規則直接確認，未顯示
+	OutcomeForwarded   Outcome = "forwarded"   // 規則轉送
+	OutcomeDuplicate   Outcome = "duplicate"   // 重複通知已合併
+	OutcomeSummarized  Outcome = "summarized"  // 以摘要通知呈現
+	OutcomeFailed      Outcome = "failed"      // 顯示失敗，稍後重試
+	OutcomeQuarantined Outcome = "quarantined" // 多次顯示失敗已隔離
+)
+
+const (
+	DefaultMaxDays    = 30   // 預設保留天數
+	DefaultMaxRecords = 5000 // 預設最多保留筆數
+)
+
+// compactEvery 是變更日誌累積多少筆後壓縮回快照
+const compactEvery = 500
+
+// Record 代表一則通知的歷史記錄
+type Record struct {
+	ID           string           `json:"id"`
+	Notification api.Notification `json:"notification"`
+	Outcome      Outcome          `json:"outcome"`
+	ReceivedAt   time.Time        `json:"received_at"`
+	DisplayedAt  time.Time        `json:"displayed_at,omitempty"`
+	AckedAt      time.Time        `json:"acked_at,omitempty"`
+	UserAckedAt  time.Time        `json:"user_acked_at,omitempty"`
+	ReadAt       time.Time        `json:"read_at,omitempty"`
+	Error        string           `json:"error,omitempty"`
+	Profile      string           `json:"profile,omitempty"` // 所屬設定檔，合併多個設定檔的歷史時填入
+}
+
+// Filter 代表歷史記錄的查詢條件，留空的條件不參與比對
+type Filter struct {
+	Project    string    // 專案（支援 * 萬用字元）
+	Priorities []string  // 優先權
+	Outcomes   []Outcome // 處理結果
+	Since      time.Time // 收到時間下限（含）
+	Until      time.Time // 收到時間上限（不含）
+	Limit      int       // 最多返回筆數，0 不限制
+}
+
+// logEntry 是變更日誌中的一行：修改後的完整記錄，或被刪除的 ID
+type logEntry struct {
+	Record *Record `json:"record,omitempty"`
+	Delete string  `json:"delete,omitempty"`
+}
+
+// Store 持久化保存收到的通知與處理結果；每次變更只在日誌（<名稱>.log）附加一行，
+// 累積 compactEvery 行後才將全部記錄寫回快照並清空日誌。全部記錄都保存在記憶體中，
+// 筆數以 max_records 限制，最多 config.MaxHistoryRecords 筆
+type Store struct {
+	mu         sync.Mutex
+	path       string
+	logPath    string
+	logLines   int
+	maxAge     time.Duration
+	maxRecords int
+	records    map[string]*Record
+}
+
+// Open 開啟（或建立）歷史記錄，讀取快照後重播變更日誌；開啟時不寫入，
+// 只讀取的命令列在程式執行中也可以安全地開啟
+func Open(path string, cfg config.History) (*Store, error) {
+	s := &Store{
+		path:       path,
+		logPath:    strings.TrimSuffix(path, filepath.Ext(path)) + ".log",
+		maxAge:     time.Duration(cfg.MaxDays) * 24 * time.Hour,
+		maxRecords: cfg.MaxRecords,
+		records:    make(map[string]*Record),
+	}
+	if cfg.MaxDays <= 0 {
+		s.maxAge = DefaultMaxDays * 24 * time.Hour
+	}
+	if s.maxRecords <= 0 {
+		s.maxRecords = DefaultMaxRecords
+	}
+	s.maxRecords = min(s.maxRecords, config.MaxHistoryRecords)
+	if err := jsonfile.Load(path, &s.records); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err := s.replay(); err != nil {
+		return nil, err
+	}
+	s.prune(time.Now())
+	return s, nil
+}
+
+// replay 依序套用變更日誌；無法解析的行（例如寫入中當機留下的最後一行）略過，並在下次寫入時壓縮
+func (s *Store) replay() error {
+	data, err := os.ReadFile(s.logPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(bytes.NewReader(data))
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return nil
+		}
+		s.logLines++
+
+		var e logEntry
+		if jsonErr := json.Unmarshal(line, &e); jsonErr == nil {
+			switch {
+			case e.Record != nil:
+				s.records[e.Record.ID] = e.Record
+			case e.Delete != "":
+				delete(s.records, e.Delete)
+			}
+		}
+		// 不完整的行會與下一次附加的內容黏在一起，因此下次寫入時改為壓縮
+		if err != nil || e.Record == nil && e.Delete == "" {
+			s.logLines = compactEvery
+		}
+		if err != nil {
+			return nil
+		}
+	}
+}
+
+// Received 記錄收到的通知，返回是否為新的記錄；已存在的記錄不會被覆寫
+func (s *Store) Received(n api.Notification, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.records[n.ID]; ok {
+		return false, nil
+	}
+	r := &Record{ID: n.ID, Notification: n, Outcome: OutcomePending, ReceivedAt: now}
+	s.records[n.ID] = r
+	return true, s.save(now, logEntry{Record: r})
+}
+
+// SetOutcome 記錄通知的處理結果，顯示成功時同時記錄顯示時間
+func (s *Store) SetOutcome(n api.Notification, outcome Outcome, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.record(n, now)
+	r.Outcome = outcome
+	r.Error = ""
+	if outcome == OutcomeDisplayed {
+		r.DisplayedAt = now
+	}
+	return s.save(now, logEntry{Record: r})
+}
+
+// RecordError 記錄顯示失敗
+func (s *Store) RecordError(n api.Notification, showErr error, quarantined bool, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.record(n, now)
+	r.Outcome = OutcomeFailed
+	if quarantined {
+		r.Outcome = OutcomeQuarantined
+	}
+	r.Error = showErr.Error()
+	return s.save(now, logEntry{Record: r})
+}
+
+// MarkAcked 記錄伺服器狀態已更新，沒有記錄的通知會被忽略
+func (s *Store) MarkAcked(id string, now time.Time) error {
+	return s.update(id, now, func(r *Record) { r.AckedAt = now })
+}
+
+// MarkUserAcked 記錄使用者已確認通知，沒有記錄的通知會被忽略
+func (s *Store) MarkUserAcked(id string, now time.Time) error {
+	return s.update(id, now, func(r *Record) {
+		if r.UserAckedAt.IsZero() {
+			r.UserAckedAt = now
+		}
+	})
+}
+
+// MarkRead 記錄使用者已在收件匣中讀取通知
+func (s *Store) MarkRead(id string, now time.Time) error {
+	return s.update(id, now, func(r *Record) {
+		if r.ReadAt.IsZero() {
+			r.ReadAt = now
+		}
+	})
+}
+
+// MarkUnread 將通知標為未讀
+func (s *Store) MarkUnread(id string, now time.Time) error {
+	return s.update(id, now, func(r *Record) { r.ReadAt = time.Time{} })
+}
+
+// MarkReverted 記錄通知已退回伺服器佇列，清除確認與已讀時間
+func (s *Store) MarkReverted(id string, now time.Time) error {
+	return s.update(id, now, func(r *Record) {
+		r.AckedAt = time.Time{}
+		r.ReadAt = time.Time{}
+	})
+}
+
+// Delete 從本機歷史中刪除記錄，不影響伺服器上的通知
+func (s *Store) Delete(id string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.records[id]; !ok {
+		return nil
+	}
+	delete(s.records, id)
+	return s.save(now, logEntry{Delete: id})
+}
+
+// Unread 返回未讀的記錄數
+func (s *Store) Unread() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, r := range s.records {
+		if r.ReadAt.IsZero() {
+			count++
+		}
+	}
+	return count
+}
+
+// Get 取得通知的歷史記錄
+func (s *Store) Get(id string) (Record, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[id]
+	if !ok {
+		return Record{}, false
+	}
+	return *r, true
+}
+
+// Query 返回符合條件的記錄（依收到時間由新到舊排序）；每次查詢都逐筆掃描全部記錄
+func (s *Store) Query(f Filter) []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var records []Record
+	for _, r := range s.records {
+		if f.Matches(*r) {
+			records = append(records, *r)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ReceivedAt.After(records[j].ReceivedAt)
+	})
+	if f.Limit > 0 && len(records) > f.Limit {
+		records = records[:f.Limit]
+	}
+	return records
+}
+
+// Len 返回記錄筆數
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.records)
+}
+
+// Matches 判斷記錄是否符合查詢條件
+func (f Filter) Matches(r Record) bool {
+	n := r.Notification
+	if f.Project != "" {
+		if ok, _ := path.Match(f.Project, n.Project); !ok {
+			return false
+		}
+	}
+	if len(f.Priorities) > 0 && !contains(f.Priorities, n.Priority) {
+		return false
+	}
+	if len(f.Outcomes) > 0 && !contains(f.Outcomes, r.Outcome) {
+		return false
+	}
+	if !f.Since.IsZero() && r.ReceivedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !r.ReceivedAt.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+func contains[T comparable](list []T, v T) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// record 取得或建立記錄（呼叫端需持有鎖）
+func (s *Store) record(n api.Notification, now time.Time) *Record {
+	r, ok := s.records[n.ID]
+	if !ok {
+		r = &Record{ID: n.ID, Notification: n, ReceivedAt: now}
+		s.records[n.ID] = r
+	}
+	return r
+}
+
+// update 修改既有記錄並寫入磁碟
+func (s *Store) update(id string, now time.Time, fn func(r *Record)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[id]
+	if !ok {
+		return nil
+	}
+	fn(r)
+	return s.save(now, logEntry{Record: r})
+}
+
+// save 將變更附加到日誌，日誌累積 compactEvery 行後改為壓縮（呼叫端需持有鎖）
+func (s *Store) save(now time.Time, e logEntry) error {
+	s.prune(now)
+	if s.logLines >= compactEvery {
+		return s.compact()
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.logPath), 0755); err != nil {
+		return fmt.Errorf("無法創建目錄: %w", err)
+	}
+	file, err := os.OpenFile(s.logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("寫入 %s 失敗: %w", s.logPath, err)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	s.logLines++
+	return nil
+}
+
+// compact 將全部記錄寫回快照後清空日誌；快照以原子方式寫入，
+// 清空日誌前當機時重播日誌的結果與快照相同（呼叫端需持有鎖）
+func (s *Store) compact() error {
+	if err := jsonfile.Save(s.path, s.records); err != nil {
+		return err
+	}
+	if err := os.Remove(s.logPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	s.logLines = 0
+	return nil
+}
+
+// prune 依保留天數與筆數移除舊記錄；只在記憶體中移除，
+// 重播日誌後再次套用相同的規則即可得到相同的結果（呼叫端需持有鎖）
+func (s *Store) prune(now time.Time) {
+	for id, r := range s.records {
+		if now.Sub(r.ReceivedAt) > s.maxAge {
+			delete(s.records, id)
+		}
+	}
+
+	if excess := len(s.records) - s.maxRecords; excess > 0 {
+		oldest := make([]*Record, 0, len(s.records))
+		for _, r := range s.records {
+			oldest = append(oldest, r)
+		}
+		sort.Slice(oldest, func(i, j int) bool {
+			return oldest[i].ReceivedAt.Before(oldest[j].ReceivedAt)
+		})
+		for _, r := range oldest[:excess] {
+			delete(s.records, r.ID)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"windows-notification/internal/api"
+	"windows-notification/internal/config"
+)
+
+func TestStoreReplaysLogAndCompacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	logPath := filepath.Join(filepath.Dir(path), "history.log")
+	now := time.Now()
+
+	s, err := Open(path, config.History{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Received(api.Notification{ID: "1", Title: "a"}, now)
+	s.Received(api.Notification{ID: "2", Title: "b"}, now)
+	s.SetOutcome(api.Notification{ID: "1"}, OutcomeDisplayed, now)
+	s.MarkRead("1", now)
+	s.Delete("2", now)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("snapshot written before compaction: %v", err)
+	}
+
+	// 重新開啟時由變更日誌還原
+	s, err = Open(path, config.History{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, ok := s.Get("1")
+	if !ok || r.Outcome != OutcomeDisplayed || r.ReadAt.IsZero() || r.Notification.Title != "a" {
+		t.Fatalf("record 1 = %+v, %v after replay", r, ok)
+	}
+	if _, ok := s.Get("2"); ok {
+		t.Fatal("deleted record 2 restored by replay")
+	}
+
+	// 累積 compactEvery 行後寫回快照並清空日誌
+	for i := 0; i < compactEvery; i++ {
+		s.MarkUnread("1", now)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("snapshot not written after compaction: %v", err)
+	}
+	if s.logLines >= compactEvery {
+		t.Fatalf("log not truncated: %d lines", s.logLines)
+	}
+
+	// 寫入中當機留下的不完整行略過，下次寫入時壓縮
+	f, _ := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	f.WriteString(`{"record":{"id":"3"`)
+	f.Close()
+	s, err = Open(path, config.History{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Len() != 1 {
+		t.Fatalf("Len() = %d after torn write, want 1", s.Len())
+	}
+	s.Received(api.Notification{ID: "4"}, now)
+	if _, err := os.Stat(logPath); !os.IsNotExist(err) {
+		t.Fatalf("torn log not compacted: %v", err)
+	}
+	if s, _ = Open(path, config.History{}); s.Len() != 2 {
+		t.Fatalf("Len() = %d after compaction, want 2", s.Len())
+	}
+}
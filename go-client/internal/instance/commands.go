@@ -0,0 +1,69 @@
+package instance
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Commands 由持有本機記錄的執行中程式實作，讓命令列的 ack 與 unread 交給它處理，
+// 避免兩個程式同時改寫投遞記錄與通知歷史；id 可為 "設定檔/ID"，未指定設定檔時為 default
+type Commands interface {
+	AckNow(id string) error
+	MarkUnread(id string, showAgain bool) error
+}
+
+// AckArgs 返回轉交 ack 的參數
+func AckArgs(ids []string) []string {
+	return append([]string{Ack}, ids...)
+}
+
+// UnreadArgs 返回轉交 unread 的參數
+func UnreadArgs(ids []string, showAgain bool) []string {
+	args := []string{Unread}
+	if showAgain {
+		args = append(args, "-show")
+	}
+	return append(args, ids...)
+}
+
+// Dispatch 處理轉交的 ack 與 unread；每則通知的結果逐行輸出，任一則失敗時錯誤列出所有失敗的通知
+func Dispatch(c Commands, args []string) (string, error) {
+	if len(args) == 0 {
+		return "", errors.New("缺少命令")
+	}
+
+	var handle func(id string) error
+	var done string
+	ids := args[1:]
+	switch args[0] {
+	case Ack:
+		handle, done = c.AckNow, "已確認"
+	case Unread:
+		showAgain := len(ids) > 0 && ids[0] == "-show"
+		if showAgain {
+			ids = ids[1:]
+		}
+		handle = func(id string) error { return c.MarkUnread(id, showAgain) }
+		done = "已標為未讀"
+	default:
+		return "", fmt.Errorf("不支援的命令: %s", strings.Join(args, " "))
+	}
+	if len(ids) == 0 {
+		return "", fmt.Errorf("%s 需要通知 ID", args[0])
+	}
+
+	var out strings.Builder
+	var failed []string
+	for _, id := range ids {
+		if err := handle(id); err != nil {
+			failed = append(failed, fmt.Sprintf("#%s %v", id, err))
+			continue
+		}
+		fmt.Fprintf(&out, "#%s %s\n", id, done)
+	}
+	if len(failed) > 0 {
+		return out.String(), errors.New(strings.Join(failed, "\n"))
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,267 @@
+package instance
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 轉交給執行中程式的命令；轉交時未帶參數即為 Show
+const (
+	Show   = "show"   // 顯示視窗
+	Ack    = "ack"    // ack <ID>...，確認通知並更新伺服器狀態
+	Unread = "unread" // unread [-show] <ID>...，將通知改回未通知
+)
+
+// timeout 是轉交命令時連線與等待回應的時間上限
+const timeout = 5 * time.Second
+
+// startupWait 是鎖定檔剛建立、內容尚未寫入時等待另一個程式啟動的時間
+const startupWait = 2 * time.Second
+
+// Handler 處理其他程式轉交的命令，返回要輸出給對方的文字
+type Handler func(args []string) (string, error)
+
+// RunningError 代表相同設定檔已有執行中的程式
+type RunningError struct {
+	PID int
+}
+
+func (e *RunningError) Error() string {
+	return fmt.Sprintf("相同設定檔已有執行中的程式 (PID: %d)", e.PID)
+}
+
+// state 是寫入鎖定檔的連線資訊
+type state struct {
+	Address string `json:"address"`
+	Token   string `json:"token"`
+	PID     int    `json:"pid"`
+}
+
+// request 是轉交給執行中程式的命令；Ping 只確認程式是否存活
+type request struct {
+	Token string   `json:"token"`
+	Args  []string `json:"args"`
+	Ping  bool     `json:"ping,omitempty"`
+}
+
+// reply 是執行中程式的回應
+type reply struct {
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Lock 代表取得的單一執行個體鎖定，同時監聽其他程式轉交的命令
+type Lock struct {
+	path     string
+	token    string
+	listener net.Listener
+
+	mu      sync.Mutex
+	handler Handler
+}
+
+// Path 返回設定檔對應的鎖定檔路徑；相同設定檔（不論相對或絕對路徑）對應同一個鎖定
+func Path(configPath string) string {
+	abs, err := filepath.Abs(configPath)
+	if err != nil {
+		abs = configPath
+	}
+	if runtime.GOOS == "windows" {
+		abs = strings.ToLower(abs)
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(os.TempDir(), "windows-notification-"+hex.EncodeToString(sum[:8])+".lock")
+}
+
+// Acquire 取得鎖定；已有執行中的程式時返回 *RunningError，
+// 程式異常結束留下的鎖定檔會被移除後重新取得
+func Acquire(path string) (*Lock, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("無法監聽轉交命令: %w", err)
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("無法產生權杖: %w", err)
+	}
+	l := &Lock{path: path, token: hex.EncodeToString(buf), listener: listener}
+
+	deadline := time.Now().Add(startupWait)
+	for {
+		err := l.create()
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			listener.Close()
+			return nil, fmt.Errorf("無法建立鎖定檔: %w", err)
+		}
+
+		s, err := readState(path)
+		if err == nil && ping(s) == nil {
+			listener.Close()
+			return nil, &RunningError{PID: s.PID}
+		}
+		// 另一個程式剛建立鎖定檔、尚未寫入內容時稍候重試
+		if err != nil && time.Now().Before(deadline) {
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			listener.Close()
+			return nil, fmt.Errorf("無法移除過期的鎖定檔: %w", err)
+		}
+	}
+
+	go l.serve()
+	return l, nil
+}
+
+// create 以獨佔方式建立鎖定檔並寫入連線資訊
+func (l *Lock) create() error {
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	s := state{Address: l.listener.Addr().String(), Token: l.token, PID: os.Getpid()}
+	if err := json.NewEncoder(f).Encode(s); err != nil {
+		f.Close()
+		os.Remove(l.path)
+		return err
+	}
+	return f.Close()
+}
+
+// Handle 設定處理轉交命令的函式；設定前收到的命令會返回錯誤
+func (l *Lock) Handle(h Handler) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.handler = h
+}
+
+// Release 停止接收轉交命令並移除鎖定檔
+func (l *Lock) Release() error {
+	l.listener.Close()
+	if s, err := readState(l.path); err == nil && s.Token != l.token {
+		return nil // 鎖定檔已被其他程式取代
+	}
+	return os.Remove(l.path)
+}
+
+// serve 接收轉交命令直到 Release
+func (l *Lock) serve() {
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			return
+		}
+		go l.handle(conn)
+	}
+}
+
+// handle 處理一個連線上的單一命令
+func (l *Lock) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	var req request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(req.Token), []byte(l.token)) != 1 {
+		json.NewEncoder(conn).Encode(reply{Error: "權杖無效"})
+		return
+	}
+	if req.Ping {
+		json.NewEncoder(conn).Encode(reply{})
+		return
+	}
+
+	l.mu.Lock()
+	h := l.handler
+	l.mu.Unlock()
+
+	var resp reply
+	if h == nil {
+		resp.Error = "執行中的程式尚未準備好"
+	} else if out, err := h(req.Args); err != nil {
+		resp = reply{Output: out, Error: err.Error()}
+	} else {
+		resp.Output = out
+	}
+	json.NewEncoder(conn).Encode(resp)
+}
+
+// readState 讀取鎖定檔中的連線資訊
+func readState(path string) (state, error) {
+	var s state
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, err
+	}
+	if s.Address == "" {
+		return s, errors.New("鎖定檔內容不完整")
+	}
+	return s, nil
+}
+
+// ping 確認鎖定檔記錄的程式是否仍在執行
+func ping(s state) error {
+	_, err := send(s, request{Token: s.Token, Ping: true})
+	return err
+}
+
+// send 送出請求並等待回應
+func send(s state, req request) (reply, error) {
+	var resp reply
+	conn, err := net.DialTimeout("tcp", s.Address, timeout)
+	if err != nil {
+		return resp, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return resp, err
+	}
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+// Forward 將命令轉交給執行中的程式並返回其輸出；未帶參數時轉交 Show
+func Forward(path string, args []string) (string, error) {
+	s, err := readState(path)
+	if err != nil {
+		return "", fmt.Errorf("無法讀取鎖定檔: %w", err)
+	}
+	if len(args) == 0 {
+		args = []string{Show}
+	}
+
+	resp, err := send(s, request{Token: s.Token, Args: args})
+	if err != nil {
+		return "", fmt.Errorf("無法轉交命令給執行中的程式: %w", err)
+	}
+	if resp.Error != "" {
+		return resp.Output, errors.New(resp.Error)
+	}
+	return resp.Output, nil
+}
@@ -0,0 +1,52 @@
+package jsonfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Load 從指定路徑讀取 JSON，檔案不存在時返回 os.ErrNotExist
+func Load(path string, v interface{}) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := json.NewDecoder(file).Decode(v); err != nil {
+		return fmt.Errorf("解析 %s 失敗: %w", path, err)
+	}
+	return nil
+}
+
+// Save 以原子方式寫入 JSON：先寫入暫存檔再重新命名，避免中途當機留下損毀的檔案
+func Save(path string, v interface{}) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("無法創建目錄 %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("無法建立暫存檔: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	encoder := json.NewEncoder(tmp)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		tmp.Close()
+		return fmt.Errorf("寫入 %s 失敗: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("寫入 %s 失敗: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("寫入 %s 失敗: %w", path, err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,266 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"windows-notification/internal/api"
+	"windows-notification/internal/jsonfile"
+)
+
+// State 代表通知在本機的投遞狀態
+type State string
+
+const (
+	StateShown      State = "shown"       // 已顯示，尚未更新伺服器狀態
+	StateAckPending State = "ack_pending" // 已處理，伺服器狀態更新失敗或排入佇列待重送
+	StateAwaiting   State = "awaiting"    // 已顯示，等待使用者確認後才更新伺服器狀態
+	StateAcked      State = "acked"       // 伺服器狀態已更新
+	StateReverted   State = "reverted"    // 已在本機標為未讀並退回伺服器佇列，不再於本機顯示
+)
+
+// DefaultRetention 是已確認項目保留的時間；已退回的項目不依時間移除，見 PruneReverted
+const DefaultRetention = 7 * 24 * time.Hour
+
+// Entry 代表一則通知的投遞記錄。State 是伺服器狀態的同步進度，
+// UserAckedAt 則是使用者實際確認的時間，兩者互相獨立
+type Entry struct {
+	ID           string           `json:"id"`
+	Notification api.Notification `json:"notification"`
+	State        State            `json:"state"`
+	Displayed    bool             `json:"displayed"`
+	ShownAt      time.Time        `json:"shown_at"`
+	AckedAt      time.Time        `json:"acked_at,omitempty"`
+	Attempts     int              `json:"attempts"`
+	LastError    string           `json:"last_error,omitempty"`
+	UserAckedAt  time.Time        `json:"user_acked_at,omitempty"`
+	Renotified   bool             `json:"renotified,omitempty"`
+	Escalated    bool             `json:"escalated,omitempty"`
+	RevertedAt   time.Time        `json:"reverted_at,omitempty"`
+}
+
+// Ledger 持久化記錄已顯示的通知，確保同一則通知只顯示一次
+type Ledger struct {
+	mu        sync.Mutex
+	path      string
+	retention time.Duration
+	entries   map[string]*Entry
+}
+
+// Open 開啟（或建立）投遞記錄
+func Open(path string) (*Ledger, error) {
+	l := &Ledger{
+		path:      path,
+		retention: DefaultRetention,
+		entries:   make(map[string]*Entry),
+	}
+	if err := jsonfile.Load(path, &l.entries); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Get 取得通知的投遞記錄
+func (l *Ledger) Get(id string) (Entry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[id]
+	if !ok {
+		return Entry{}, false
+	}
+	return *e, true
+}
+
+// Delivered 判斷通知是否已顯示但尚未確認，這類通知不應再次顯示
+func (l *Ledger) Delivered(id string) bool {
+	e, ok := l.Get(id)
+	return ok && e.State != StateAcked && e.State != StateReverted
+}
+
+// Awaiting 判斷通知是否已顯示並等待使用者確認
+func (l *Ledger) Awaiting(id string) bool {
+	e, ok := l.Get(id)
+	return ok && e.State == StateAwaiting
+}
+
+// Reverted 判斷通知是否已由本機退回伺服器佇列
+func (l *Ledger) Reverted(id string) bool {
+	e, ok := l.Get(id)
+	return ok && e.State == StateReverted
+}
+
+// MarkShown 記錄通知已顯示
+func (l *Ledger) MarkShown(n api.Notification, now time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries[n.ID] = &Entry{
+		ID:           n.ID,
+		Notification: n,
+		State:        StateShown,
+		Displayed:    true,
+		ShownAt:      now,
+	}
+	return l.save(now)
+}
+
+// MarkAckPending 記錄伺服器狀態尚未更新、已排入佇列待重送；ackErr 為 nil 表示
+// 未嘗試送出，只因佇列中仍有較早的更新而排在後面
+func (l *Ledger) MarkAckPending(n api.Notification, now time.Time, ackErr error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := l.entry(n, now)
+	e.State = StateAckPending
+	if ackErr != nil {
+		e.Attempts++
+		e.LastError = ackErr.Error()
+	}
+	return l.save(now)
+}
+
+// MarkAcked 記錄伺服器狀態已更新
+func (l *Ledger) MarkAcked(n api.Notification, now time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := l.entry(n, now)
+	e.State = StateAcked
+	e.Attempts++
+	e.AckedAt = now
+	e.LastError = ""
+	return l.save(now)
+}
+
+// MarkReverted 記錄通知已標為未讀並退回伺服器佇列，之後伺服器再回傳時不會在本機重新顯示
+func (l *Ledger) MarkReverted(n api.Notification, now time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := l.entry(n, now)
+	e.State = StateReverted
+	e.RevertedAt = now
+	e.LastError = ""
+	return l.save(now)
+}
+
+// Forget 移除通知的投遞記錄，伺服器再回傳時會重新顯示
+func (l *Ledger) Forget(id string, now time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.entries, id)
+	return l.save(now)
+}
+
+// entry 取得或建立記錄（呼叫端需持有鎖）；未經顯示而直接確認的通知也會被記錄
+func (l *Ledger) entry(n api.Notification, now time.Time) *Entry {
+	e, ok := l.entries[n.ID]
+	if !ok {
+		e = &Entry{ID: n.ID, Notification: n, ShownAt: now}
+		l.entries[n.ID] = e
+	}
+	return e
+}
+
+// MarkAwaiting 記錄通知已顯示，等待使用者確認後才更新伺服器狀態
+func (l *Ledger) MarkAwaiting(id string, now time.Time) error {
+	return l.update(id, now, func(e *Entry) { e.State = StateAwaiting })
+}
+
+// MarkUserAcked 記錄使用者已確認通知
+func (l *Ledger) MarkUserAcked(id string, now time.Time) error {
+	return l.update(id, now, func(e *Entry) {
+		if e.UserAckedAt.IsZero() {
+			e.UserAckedAt = now
+		}
+	})
+}
+
+// MarkRenotified 記錄已重新提醒
+func (l *Ledger) MarkRenotified(id string, now time.Time) error {
+	return l.update(id, now, func(e *Entry) { e.Renotified = true })
+}
+
+// MarkEscalated 記錄已升級到次要通道
+func (l *Ledger) MarkEscalated(id string, now time.Time) error {
+	return l.update(id, now, func(e *Entry) { e.Escalated = true })
+}
+
+// update 修改既有記錄並寫入磁碟，記錄不存在時返回錯誤
+func (l *Ledger) update(id string, now time.Time, fn func(e *Entry)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[id]
+	if !ok {
+		return fmt.Errorf("找不到通知 %s 的投遞記錄", id)
+	}
+	fn(e)
+	return l.save(now)
+}
+
+// AwaitingUserAck 返回已顯示但使用者尚未確認的記錄（依顯示時間排序）
+func (l *Ledger) AwaitingUserAck() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var entries []Entry
+	for _, e := range l.entries {
+		if e.Displayed && e.UserAckedAt.IsZero() && e.State != StateReverted {
+			entries = append(entries, *e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ShownAt.Before(entries[j].ShownAt)
+	})
+	return entries
+}
+
+// Pending 返回所有尚未確認的記錄（不含已退回的記錄）
+func (l *Ledger) Pending() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var pending []Entry
+	for _, e := range l.entries {
+		if e.State != StateAcked && e.State != StateReverted {
+			pending = append(pending, *e)
+		}
+	}
+	return pending
+}
+
+// PruneReverted 移除 keep 返回 false 的已退回記錄；伺服器不再回傳未通知的通知代表已由其他客戶端處理，
+// 之後不需要再阻擋本機顯示
+func (l *Ledger) PruneReverted(keep func(id string) bool, now time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	removed := false
+	for id, e := range l.entries {
+		if e.State == StateReverted && !keep(id) {
+			delete(l.entries, id)
+			removed = true
+		}
+	}
+	if !removed {
+		return nil
+	}
+	return l.save(now)
+}
+
+// save 壓縮過期記錄後寫入磁碟（呼叫端需持有鎖）
+func (l *Ledger) save(now time.Time) error {
+	for id, e := range l.entries {
+		if e.State == StateAcked && now.Sub(e.AckedAt) > l.retention {
+			delete(l.entries, id)
+		}
+	}
+	return jsonfile.Save(l.path, l.entries)
+}
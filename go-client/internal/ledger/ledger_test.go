@@ -0,0 +1,93 @@
+package ledger
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"windows-notification/internal/api"
+)
+
+func TestStateTransitions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	n := api.Notification{ID: "1", Title: "Deploy"}
+
+	type check struct{ delivered, awaiting, reverted bool }
+	steps := []struct {
+		name  string
+		apply func() error
+		state State
+		want  check
+	}{
+		{"shown", func() error { return l.MarkShown(n, now) }, StateShown, check{delivered: true}},
+		{"awaiting", func() error { return l.MarkAwaiting("1", now) }, StateAwaiting, check{delivered: true, awaiting: true}},
+		{"queued", func() error { return l.MarkAckPending(n, now, nil) }, StateAckPending, check{delivered: true}},
+		{"failed", func() error { return l.MarkAckPending(n, now, errors.New("timeout")) }, StateAckPending, check{delivered: true}},
+		{"acked", func() error { return l.MarkAcked(n, now) }, StateAcked, check{}},
+		{"reverted", func() error { return l.MarkReverted(n, now) }, StateReverted, check{reverted: true}},
+	}
+	for _, s := range steps {
+		if err := s.apply(); err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		e, _ := l.Get("1")
+		got := check{l.Delivered("1"), l.Awaiting("1"), l.Reverted("1")}
+		if e.State != s.state || got != s.want {
+			t.Errorf("%s: state = %s %+v, want %s %+v", s.name, e.State, got, s.state, s.want)
+		}
+	}
+
+	// 排入佇列不計入失敗次數，只有實際送出失敗才記錄錯誤
+	if e, _ := l.Get("1"); e.Attempts != 2 || e.LastError != "" || !e.Displayed {
+		t.Errorf("entry = %+v, want 2 attempts, no error and displayed", e)
+	}
+	if err := l.MarkAwaiting("missing", now); err == nil {
+		t.Error("MarkAwaiting() on a missing entry succeeded")
+	}
+
+	l, err = Open(path)
+	if err != nil || !l.Reverted("1") {
+		t.Fatalf("reopen: %v, reverted = %v", err, l.Reverted("1"))
+	}
+}
+
+func TestCompaction(t *testing.T) {
+	l, err := Open(filepath.Join(t.TempDir(), "ledger.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, id := range []string{"acked", "reverted", "shown"} {
+		l.MarkShown(api.Notification{ID: id}, now)
+	}
+	l.MarkAcked(api.Notification{ID: "acked"}, now)
+	l.MarkReverted(api.Notification{ID: "reverted"}, now)
+
+	// 已確認的記錄超過保留時間後移除，已退回與未確認的記錄保留
+	later := now.Add(DefaultRetention + time.Hour)
+	l.MarkShown(api.Notification{ID: "new"}, later)
+	for id, want := range map[string]bool{"acked": false, "reverted": true, "shown": true, "new": true} {
+		if _, ok := l.Get(id); ok != want {
+			t.Errorf("Get(%s) present = %v, want %v", id, ok, want)
+		}
+	}
+	if n := len(l.Pending()); n != 2 {
+		t.Errorf("Pending() = %d, want 2", n)
+	}
+
+	// 伺服器不再回傳的已退回記錄才移除
+	l.PruneReverted(func(id string) bool { return true }, later)
+	if !l.Reverted("reverted") {
+		t.Error("PruneReverted() removed an entry the server still returns")
+	}
+	l.PruneReverted(func(id string) bool { return false }, later)
+	if _, ok := l.Get("reverted"); ok {
+		t.Error("PruneReverted() kept an entry the server no longer returns")
+	}
+}
@@ -2,6 +2,7 @@ package logger
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
@@ -45,6 +46,7 @@ type Logger struct {
 	file        *os.File
 	mu          sync.Mutex
 	guiCallback GUICallback
+	console     io.Writer
 	debugMode   bool
 }
 
@@ -85,6 +87,13 @@ func (l *Logger) SetGUICallback(callback GUICallback) {
 	l.guiCallback = callback
 }
 
+// SetConsole 設定額外的輸出目的地（例如標準輸出），nil 表示只寫入檔案
+func (l *Logger) SetConsole(w io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.console = w
+}
+
 // SetDebugMode 設定 debug 模式
 func (l *Logger) SetDebugMode(enabled bool) {
 	l.mu.Lock()
@@ -103,6 +112,11 @@ func (l *Logger) log(level Level, message string) {
 		l.file.WriteString(logLine)
 	}
 
+	// 寫入主控台（無視窗模式）
+	if l.console != nil {
+		io.WriteString(l.console, logLine)
+	}
+
 	// 通知 GUI（如果有回調）
 	if l.guiCallback != nil {
 		// 格式化給 GUI 的消息（簡短時間格式）
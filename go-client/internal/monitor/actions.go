@@ -0,0 +1,306 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+
+	"windows-notification/internal/actions"
+	"windows-notification/internal/api"
+	"windows-notification/internal/config"
+	"windows-notification/internal/escalation"
+	"windows-notification/internal/ledger"
+	"windows-notification/internal/notification"
+	"windows-notification/internal/scheduler"
+)
+
+// toastOptions 建立通知的動作按鈕；需要使用者確認的通知會加上「確認」按鈕，
+// on_click 模式下點擊通知本身也視為確認
+func (e *Engine) toastOptions(notif api.Notification, urgent bool) notification.Options {
+	opts := notification.Options{
+		LaunchURL: notif.ActionURL,
+		Urgent:    urgent,
+	}
+	if e.actions == nil {
+		return opts
+	}
+	if e.cfg.AckMode == config.AckOnClick {
+		opts.LaunchURL = e.actions.URL(actions.Open, notif.ID, notif.ActionURL)
+	}
+	if e.cfg.ExplicitAck() || e.escalation.RequiresAck(notif) {
+		opts.Actions = append(opts.Actions, notification.Action{
+			Label: "Acknowledge",
+			URL:   e.actions.URL(actions.Ack, notif.ID, ""),
+		})
+	}
+	if e.CanSnooze() {
+		for _, choice := range scheduler.Choices {
+			opts.Actions = append(opts.Actions, notification.Action{
+				Label: choice.Label,
+				URL:   e.actions.URL(actions.Snooze, notif.ID, choice.Delay),
+			})
+		}
+	}
+	return opts
+}
+
+// HandleAction 處理通知上的動作按鈕，供 actions.Server 呼叫
+func (e *Engine) HandleAction(action, id, arg string) error {
+	switch action {
+	case actions.Ack, actions.Open:
+		return e.Ack(id)
+	case actions.Snooze:
+		return e.SnoozeByID(id, arg)
+	default:
+		return fmt.Errorf("未知的動作 %q", action)
+	}
+}
+
+// Ack 記錄使用者已確認通知，停止後續的重新提醒與升級；
+// 等待確認的通知同時更新伺服器狀態
+func (e *Engine) Ack(id string) error {
+	entry, err := e.markUserAcked(id)
+	if err != nil {
+		return err
+	}
+	if entry.State == ledger.StateAwaiting {
+		go e.ackAwaiting(entry.Notification)
+	}
+	return nil
+}
+
+// ackAwaiting 在背景更新使用者已確認的通知狀態；與 Check 使用同一把鎖以保持狀態更新的順序，
+// 取得鎖之前已被標為未讀的通知不再更新
+func (e *Engine) ackAwaiting(notif api.Notification) {
+	e.checkMu.Lock()
+	defer e.checkMu.Unlock()
+
+	if entry, ok := e.ledger.Get(notif.ID); !ok || entry.State != ledger.StateAwaiting {
+		return
+	}
+	e.markNotified(notif, "已確認")
+}
+
+// AckNow 確認通知並立即將伺服器狀態更新為已通知，返回更新結果；
+// 不在投遞記錄中的通知也會更新伺服器狀態，供命令列的 ack 使用
+func (e *Engine) AckNow(id string) error {
+	e.checkMu.Lock()
+	defer e.checkMu.Unlock()
+
+	notif := api.Notification{ID: id}
+	if e.ledger != nil {
+		if _, ok := e.ledger.Get(id); ok {
+			entry, err := e.markUserAcked(id)
+			if err != nil {
+				return err
+			}
+			if entry.State == ledger.StateAcked {
+				return nil
+			}
+			notif = entry.Notification
+		}
+	}
+
+	// outbox 中仍有較早的更新時排在後面，以保持順序
+	if e.outbox != nil && e.outbox.Len() > 0 {
+		e.deferAck(notif, nil)
+		return nil
+	}
+	if err := e.api().SetStatus(context.Background(), id, api.StatusNotified); err != nil {
+		return err
+	}
+	e.recordAcked(notif, "已確認")
+	return nil
+}
+
+// markUserAcked 在投遞記錄與通知歷史中記錄使用者已確認，返回更新後的投遞記錄
+func (e *Engine) markUserAcked(id string) (ledger.Entry, error) {
+	if e.ledger == nil {
+		return ledger.Entry{}, fmt.Errorf("投遞記錄無法使用")
+	}
+	now := e.clock.Now()
+	if err := e.ledger.MarkUserAcked(id, now); err != nil {
+		return ledger.Entry{}, err
+	}
+	if e.history != nil {
+		err := e.history.MarkUserAcked(id, now)
+		if err == nil {
+			err = e.history.MarkRead(id, now)
+		}
+		if err != nil {
+			e.logWarn("儲存通知歷史失敗: %v", err)
+		}
+	}
+	if e.logger != nil {
+		e.logger.Successf("使用者已確認通知 (ID: %s)", id)
+	}
+
+	entry, _ := e.ledger.Get(id)
+	e.emit(Event{Type: EventUpdated, Notification: entry.Notification})
+	return entry, nil
+}
+
+// escalate 對超過時限仍未確認的通知重新提醒或送到次要通道
+func (e *Engine) escalate() {
+	if e.ledger == nil {
+		return
+	}
+
+	now := e.clock.Now()
+	for _, entry := range e.ledger.AwaitingUserAck() {
+		step, policy := e.escalation.Next(entry, now)
+		notif := entry.Notification
+
+		switch step {
+		case escalation.StepRenotify:
+			backend, err := e.backends.Get("")
+			if err == nil {
+				err = notification.ShowWith(backend, e.profileLabel()+"未確認: "+notif.Title, notif.Message, e.toastOptions(notif, true))
+			}
+			if err != nil {
+				e.logError("重新提醒失敗 (ID: %s): %v", notif.ID, err)
+				continue
+			}
+			e.logWarn("通知 %d 分鐘未確認，已重新提醒 (ID: %s)", policy.RenotifyAfter, notif.ID)
+			if err := e.ledger.MarkRenotified(notif.ID, now); err != nil {
+				e.logWarn("儲存投遞記錄失敗: %v", err)
+			}
+
+		case escalation.StepEscalate:
+			backend, err := e.backends.Get(policy.Notifier)
+			if err == nil {
+				err = backend.Show(fmt.Sprintf("%s[未確認 %d 分鐘] %s", e.profileLabel(), policy.EscalateAfter, notif.Title), notif.Message)
+			}
+			if err != nil {
+				e.logError("升級通知失敗 (ID: %s): %v", notif.ID, err)
+				continue
+			}
+			e.logWarn("通知 %d 分鐘未確認，已升級到 %s (ID: %s)", policy.EscalateAfter, policy.Notifier, notif.ID)
+			if err := e.ledger.MarkEscalated(notif.ID, now); err != nil {
+				e.logWarn("儲存投遞記錄失敗: %v", err)
+			}
+		}
+	}
+}
+
+// RequiresAck 判斷通知是否需要使用者明確確認
+func (e *Engine) RequiresAck(entry ledger.Entry) bool {
+	return entry.State == ledger.StateAwaiting || e.escalation.RequiresAck(entry.Notification)
+}
+
+// SnoozeByID 依投遞記錄中的通知內容延後提醒
+func (e *Engine) SnoozeByID(id, delay string) error {
+	if e.ledger == nil {
+		return fmt.Errorf("投遞記錄無法使用")
+	}
+	entry, ok := e.ledger.Get(id)
+	if !ok {
+		return fmt.Errorf("找不到通知 %s 的投遞記錄", id)
+	}
+	return e.Snooze(entry.Notification, delay)
+}
+
+// CanSnooze 判斷是否可以延後提醒；on_display 模式下顯示時已更新伺服器狀態，
+// 其他客戶端會視為已處理，因此只在明確確認模式下延後，到期確認後才更新伺服器狀態
+func (e *Engine) CanSnooze() bool {
+	return e.scheduler != nil && e.cfg.ExplicitAck()
+}
+
+// Snooze 延後通知，到期時重新顯示；延後期間視為使用者已回應，暫停升級
+func (e *Engine) Snooze(notif api.Notification, delay string) error {
+	if e.scheduler == nil {
+		return fmt.Errorf("延後提醒無法使用")
+	}
+	if !e.cfg.ExplicitAck() {
+		return fmt.Errorf("延後提醒只能用於 %s 或 %s 確認模式", config.AckOnClick, config.AckManual)
+	}
+
+	now := e.clock.Now()
+	due, err := scheduler.ParseDelay(delay, now)
+	if err != nil {
+		return err
+	}
+	if err := e.scheduler.Snooze(notif, "", due, now); err != nil {
+		return err
+	}
+
+	if e.ledger != nil {
+		if _, ok := e.ledger.Get(notif.ID); ok {
+			if err := e.ledger.MarkUserAcked(notif.ID, now); err != nil {
+				e.logWarn("儲存投遞記錄失敗: %v", err)
+			}
+		}
+	}
+	if e.logger != nil {
+		e.logger.Infof("通知已延後到 %s (ID: %s): %s", due.Format("01-02 15:04"), notif.ID, notif.Title)
+	}
+	e.emit(Event{Type: EventUpdated, Notification: notif})
+	return nil
+}
+
+// MarkUnreadByID 依投遞記錄中的通知內容標為未讀；沒有記錄時只以 ID 更新
+func (e *Engine) MarkUnreadByID(id string, showAgain bool) error {
+	notif := api.Notification{ID: id}
+	if e.ledger != nil {
+		if entry, ok := e.ledger.Get(id); ok {
+			notif = entry.Notification
+		}
+	}
+	return e.MarkUnread(notif, showAgain)
+}
+
+// MarkUnread 將通知在伺服器上改回未通知，讓其他客戶端重新處理；
+// showAgain 為 false 時本機不會再顯示，為 true 時下次查詢會重新顯示
+func (e *Engine) MarkUnread(notif api.Notification, showAgain bool) error {
+	e.checkMu.Lock()
+	defer e.checkMu.Unlock()
+
+	now := e.clock.Now()
+
+	// 先更新本機記錄，避免伺服器狀態改變後的下一次查詢立即重新顯示
+	if e.ledger != nil {
+		var err error
+		if showAgain {
+			err = e.ledger.Forget(notif.ID, now)
+		} else {
+			err = e.ledger.MarkReverted(notif, now)
+		}
+		if err != nil {
+			e.logWarn("儲存投遞記錄失敗: %v", err)
+		}
+	}
+	if e.history != nil {
+		if err := e.history.MarkReverted(notif.ID, now); err != nil {
+			e.logWarn("儲存通知歷史失敗: %v", err)
+		}
+	}
+	defer e.emit(Event{Type: EventUpdated, Notification: notif})
+
+	// outbox 中仍有較早的更新時排在後面，以保持順序
+	if e.outbox != nil && e.outbox.Len() > 0 {
+		if err := e.outbox.Enqueue(notif, api.StatusUnnotified, now); err != nil {
+			return fmt.Errorf("儲存狀態更新佇列失敗: %w", err)
+		}
+		return nil
+	}
+
+	if err := e.api().SetStatus(context.Background(), notif.ID, api.StatusUnnotified); err != nil {
+		if api.IsPermanent(err) || e.outbox == nil {
+			return err
+		}
+		e.logError("標為未讀失敗，稍後重送 (ID: %s): %v", notif.ID, err)
+		if err := e.outbox.Enqueue(notif, api.StatusUnnotified, now); err != nil {
+			return fmt.Errorf("儲存狀態更新佇列失敗: %w", err)
+		}
+		return nil
+	}
+
+	e.recordReverted(notif)
+	return nil
+}
+
+// recordReverted 記錄伺服器狀態已改回未通知
+func (e *Engine) recordReverted(notif api.Notification) {
+	if e.logger != nil {
+		e.logger.Successf("已標為未讀: %s", notif.Title)
+	}
+}
@@ -0,0 +1,536 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"windows-notification/internal/api"
+	"windows-notification/internal/config"
+	"windows-notification/internal/escalation"
+	"windows-notification/internal/history"
+	"windows-notification/internal/ledger"
+	"windows-notification/internal/logger"
+	"windows-notification/internal/notification"
+	"windows-notification/internal/outbox"
+	"windows-notification/internal/quiethours"
+	"windows-notification/internal/ratelimit"
+	"windows-notification/internal/render"
+	"windows-notification/internal/rules"
+	"windows-notification/internal/scheduler"
+)
+
+// digestMaxLines 是勿擾摘要通知中最多列出的通知數
+const digestMaxLines = 5
+
+// eventBuffer 是每個訂閱者的事件緩衝數量
+const eventBuffer = 64
+
+// maxBackoff 是連續查詢失敗時兩次查詢的最長間隔
+const maxBackoff = 5 * time.Minute
+
+// Source 是通知來源，由 api.Client 實作
+type Source interface {
+	GetUnnotifiedNotifications(project string) ([]api.Notification, error)
+	SetStatus(ctx context.Context, id string, status int) error
+	CreateNotification(req api.CreateRequest) (*api.Notification, error)
+}
+
+// Backends 依名稱取得通知後端，由 notification.Registry 實作
+type Backends interface {
+	Get(name string) (notification.Backend, error)
+}
+
+// Actions 產生通知動作按鈕的網址，由 actions.Server 實作
+type Actions interface {
+	URL(action, id, arg string) string
+}
+
+// Clock 提供目前時間，測試時可替換
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock 使用系統時間
+type SystemClock struct{}
+
+// Now 返回目前時間
+func (SystemClock) Now() time.Time { return time.Now() }
+
+// Ledger 是投遞記錄，由 ledger.Ledger 實作
+type Ledger interface {
+	Get(id string) (ledger.Entry, bool)
+	Delivered(id string) bool
+	Awaiting(id string) bool
+	Reverted(id string) bool
+	MarkShown(n api.Notification, now time.Time) error
+	MarkAwaiting(id string, now time.Time) error
+	MarkAckPending(n api.Notification, now time.Time, ackErr error) error
+	MarkAcked(n api.Notification, now time.Time) error
+	MarkUserAcked(id string, now time.Time) error
+	MarkRenotified(id string, now time.Time) error
+	MarkEscalated(id string, now time.Time) error
+	MarkReverted(n api.Notification, now time.Time) error
+	Forget(id string, now time.Time) error
+	PruneReverted(keep func(id string) bool, now time.Time) error
+	AwaitingUserAck() []ledger.Entry
+}
+
+// Outbox 是離線狀態更新佇列，由 outbox.Outbox 實作
+type Outbox interface {
+	Enqueue(n api.Notification, status int, now time.Time) error
+	Contains(id string) bool
+	Len() int
+	Oldest() time.Time
+	Flush(now time.Time, force bool, send outbox.SendFunc) (int, error)
+}
+
+// History 是通知歷史，由 history.Store 實作
+type History interface {
+	Received(n api.Notification, now time.Time) (bool, error)
+	SetOutcome(n api.Notification, outcome history.Outcome, now time.Time) error
+	RecordError(n api.Notification, showErr error, quarantined bool, now time.Time) error
+	MarkAcked(id string, now time.Time) error
+	MarkUserAcked(id string, now time.Time) error
+	MarkRead(id string, now time.Time) error
+	MarkReverted(id string, now time.Time) error
+}
+
+// Quarantine 是顯示失敗隔離清單，由 quarantine.Store 實作
+type Quarantine interface {
+	Contains(id string) bool
+	RecordFailure(n api.Notification, failure error, now time.Time) (bool, error)
+	RecordSuccess(id string) error
+}
+
+// Scheduler 是延後提醒排程，由 scheduler.Scheduler 實作
+type Scheduler interface {
+	Contains(id string) bool
+	Snooze(n api.Notification, notifier string, due time.Time, now time.Time) error
+	Run(ctx context.Context, deliver scheduler.DeliverFunc)
+}
+
+// Dedup 是重複通知合併，由 dedup.Deduper 實作
+type Dedup interface {
+	IsDuplicate(n api.Notification, now time.Time) (bool, error)
+	Pending(n api.Notification) int
+	Shown(n api.Notification, now time.Time) error
+}
+
+// Options 是建立引擎所需的相依物件；Ledger 之後的欄位可為 nil，代表停用對應功能
+type Options struct {
+	Config   *config.Config
+	Profile  string // 設定檔名稱，非空時顯示在通知標題前
+	Source   Source
+	Backends Backends
+	Actions  Actions // 為 nil 時通知不顯示動作按鈕
+	Clock    Clock   // 預設為 SystemClock
+	Logger   *logger.Logger
+
+	Ledger     Ledger
+	Outbox     Outbox
+	History    History
+	Quarantine Quarantine
+	Scheduler  Scheduler
+	Dedup      Dedup
+}
+
+// Health 代表與伺服器的連線狀態
+type Health string
+
+const (
+	HealthUnknown  Health = "unknown"  // 尚未查詢
+	HealthOK       Health = "ok"       // 查詢成功
+	HealthDegraded Health = "degraded" // 查詢成功但仍有待重送的狀態更新
+	HealthDown     Health = "down"     // 查詢失敗
+)
+
+// EventType 代表引擎事件的類型
+type EventType string
+
+const (
+	EventFetched       EventType = "fetched"        // 完成一次查詢，Count 為未通知數
+	EventDisplayed     EventType = "displayed"      // 通知已顯示
+	EventAcked         EventType = "acked"          // 伺服器狀態已更新為已通知
+	EventUpdated       EventType = "updated"        // 本機狀態改變，例如使用者確認、延後或標為未讀
+	EventError         EventType = "error"          // 查詢、顯示或更新狀態失敗
+	EventHealthChanged EventType = "health_changed" // 連線狀態改變
+)
+
+// Event 代表引擎發出的事件
+type Event struct {
+	Type         EventType
+	Time         time.Time
+	Notification api.Notification // 相關的通知（可為空）
+	Count        int
+	Health       Health
+	Err          error
+}
+
+// Status 是引擎目前的狀態
+type Status struct {
+	Running      bool
+	Health       Health
+	LastCheck    time.Time
+	LastError    string
+	Failures     int       // 連續查詢失敗次數
+	NextCheck    time.Time // 下次定時查詢的時間，未監控時為零值
+	Queued       int       // 限流佇列中的通知數
+	Outbox       int       // 待重送的狀態更新數
+	OutboxOldest time.Time // 最舊一筆待重送更新的時間
+}
+
+// Engine 負責查詢、顯示與更新通知狀態，與 GUI 無關
+type Engine struct {
+	cfg      *config.Config
+	profile  string
+	backends Backends
+	actions  Actions
+	clock    Clock
+	logger   *logger.Logger
+
+	ledger     Ledger
+	outbox     Outbox
+	history    History
+	quarantine Quarantine
+	scheduler  Scheduler
+	dedup      Dedup
+
+	rules      *rules.Engine
+	limiter    *ratelimit.Limiter
+	templates  *render.Set
+	escalation *escalation.Policies
+	quiet      *quiethours.Controller
+
+	checkMu sync.Mutex // 同一時間只執行一次查詢、送出延後的通知或更新伺服器狀態，保持投遞記錄與 outbox 的順序
+
+	mu        sync.Mutex
+	source    Source
+	running   bool
+	cancel    context.CancelFunc
+	done      chan struct{}
+	health    Health
+	lastCheck time.Time
+	lastError string
+	failures  int
+	nextCheck time.Time
+	subs      map[chan Event]struct{}
+	closeFn   context.CancelFunc
+}
+
+// New 建立引擎並啟動延後提醒排程；規則、樣板等設定錯誤時記錄並退回預設行為
+func New(opts Options) *Engine {
+	e := &Engine{
+		cfg:        opts.Config,
+		profile:    opts.Profile,
+		source:     opts.Source,
+		backends:   opts.Backends,
+		actions:    opts.Actions,
+		clock:      opts.Clock,
+		logger:     opts.Logger,
+		ledger:     opts.Ledger,
+		outbox:     opts.Outbox,
+		history:    opts.History,
+		quarantine: opts.Quarantine,
+		scheduler:  opts.Scheduler,
+		dedup:      opts.Dedup,
+		health:     HealthUnknown,
+		subs:       make(map[chan Event]struct{}),
+	}
+	if e.clock == nil {
+		e.clock = SystemClock{}
+	}
+
+	var err error
+
+	// 建立勿擾控制器，設定錯誤時停用排程但保留手動暫停
+	e.quiet, err = quiethours.New(e.cfg.QuietHours)
+	if err != nil {
+		e.logError("勿擾設定無效，已停用排程: %v", err)
+		e.quiet, _ = quiethours.New(config.QuietHours{})
+	}
+
+	// 編譯通知規則，設定錯誤時退回預設行為
+	e.rules, err = rules.New(e.cfg.Rules)
+	if err != nil {
+		e.logError("通知規則無效，已停用規則: %v", err)
+		e.rules, _ = rules.New(nil)
+	}
+
+	// 樣板已在載入設定時驗證
+	e.templates, err = render.NewSet(e.cfg.Templates)
+	if err != nil {
+		e.templates, _ = render.NewSet(nil)
+	}
+
+	e.escalation, err = escalation.New(e.cfg.Escalation)
+	if err != nil {
+		e.logError("升級政策無效，已停用: %v", err)
+		e.escalation, _ = escalation.New(nil)
+	}
+
+	if e.cfg.RateLimit.Enabled {
+		e.limiter = ratelimit.New(e.cfg.RateLimit)
+	}
+
+	// 延後提醒在引擎存在期間持續運作，與是否監控無關
+	ctx, cancel := context.WithCancel(context.Background())
+	e.closeFn = cancel
+	if e.scheduler != nil {
+		go e.scheduler.Run(ctx, e.deliverSnoozed)
+	}
+	return e
+}
+
+// Start 開始定時查詢
+func (e *Engine) Start() error {
+	e.mu.Lock()
+	if e.running {
+		e.mu.Unlock()
+		return fmt.Errorf("監控已在執行中")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e.running = true
+	e.cancel = cancel
+	e.done = make(chan struct{})
+	done := e.done
+	e.mu.Unlock()
+
+	if e.logger != nil {
+		e.logger.Infof("監控已啟動 - %s專案: %s, 間隔: %d 秒", e.profileLabel(), e.cfg.Project, e.cfg.Interval)
+		e.logger.Infof("API 端點: %s", e.cfg.Domain)
+	}
+
+	go e.loop(ctx, done)
+	return nil
+}
+
+// Stop 停止定時查詢，等待進行中的查詢結束後返回
+func (e *Engine) Stop() {
+	e.mu.Lock()
+	if !e.running {
+		e.mu.Unlock()
+		return
+	}
+	cancel, done := e.cancel, e.done
+	e.running = false
+	e.mu.Unlock()
+
+	cancel()
+	<-done
+
+	if e.logger != nil {
+		e.logger.Success("監控已成功停止")
+	}
+}
+
+// Close 停止監控與延後提醒排程
+func (e *Engine) Close() {
+	e.Stop()
+	e.closeFn()
+}
+
+// loop 立即查詢一次，之後依間隔定時查詢
+func (e *Engine) loop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	interval := e.cfg.Interval
+	if interval <= 0 {
+		interval = 5
+	}
+
+	if e.logger != nil {
+		e.logger.Debug("執行首次通知檢查...")
+	}
+	for {
+		e.Check()
+
+		// 連續失敗時拉長間隔，避免對無法連線的伺服器持續重試
+		e.mu.Lock()
+		failures := e.failures
+		delay := backoff(time.Duration(interval)*time.Second, failures)
+		e.nextCheck = e.clock.Now().Add(delay)
+		e.mu.Unlock()
+		if failures > 0 && e.logger != nil {
+			e.logger.Debugf("%s連續查詢失敗 %d 次，%s 後重試", e.profileLabel(), failures, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			e.mu.Lock()
+			e.nextCheck = time.Time{}
+			e.mu.Unlock()
+			if e.logger != nil {
+				e.logger.Debug("監控迴圈收到取消信號，正在退出...")
+			}
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff 返回下次查詢前的等待時間；每次連續失敗間隔加倍，最長 maxBackoff
+func backoff(interval time.Duration, failures int) time.Duration {
+	d := interval
+	for i := 0; i < failures && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff && interval < maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+// Profile 返回引擎的設定檔名稱
+func (e *Engine) Profile() string {
+	return e.profile
+}
+
+// profileLabel 返回日誌中的設定檔標示，未指定設定檔時為空
+func (e *Engine) profileLabel() string {
+	if e.profile == "" {
+		return ""
+	}
+	return "[" + e.profile + "] "
+}
+
+// Status 返回引擎目前的狀態
+func (e *Engine) Status() Status {
+	e.mu.Lock()
+	s := Status{
+		Running:   e.running,
+		Health:    e.health,
+		LastCheck: e.lastCheck,
+		LastError: e.lastError,
+		Failures:  e.failures,
+		NextCheck: e.nextCheck,
+	}
+	e.mu.Unlock()
+
+	if e.limiter != nil {
+		s.Queued = e.limiter.Len()
+	}
+	if e.outbox != nil {
+		s.Outbox = e.outbox.Len()
+		if s.Outbox > 0 {
+			s.OutboxOldest = e.outbox.Oldest()
+		}
+	}
+	return s
+}
+
+// Subscribe 訂閱引擎事件，返回事件通道與取消訂閱的函式；
+// 訂閱者處理太慢時事件會被丟棄，不會阻塞引擎
+func (e *Engine) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, eventBuffer)
+	e.mu.Lock()
+	e.subs[ch] = struct{}{}
+	e.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			e.mu.Lock()
+			delete(e.subs, ch)
+			e.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// emit 發送事件給所有訂閱者
+func (e *Engine) emit(ev Event) {
+	ev.Time = e.clock.Now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for ch := range e.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// setHealth 更新連線狀態，改變時發出事件
+func (e *Engine) setHealth(h Health, checkErr error) {
+	e.mu.Lock()
+	changed := e.health != h
+	e.health = h
+	e.lastCheck = e.clock.Now()
+	e.lastError = ""
+	if checkErr != nil {
+		e.lastError = checkErr.Error()
+	}
+	if h == HealthDown {
+		e.failures++
+	} else {
+		e.failures = 0
+	}
+	e.mu.Unlock()
+
+	if changed {
+		e.emit(Event{Type: EventHealthChanged, Health: h, Err: checkErr})
+	}
+}
+
+// SetSource 更換通知來源，例如設定中的網域改變時
+func (e *Engine) SetSource(source Source) {
+	e.mu.Lock()
+	e.source = source
+	e.mu.Unlock()
+}
+
+// api 返回目前的通知來源
+func (e *Engine) api() Source {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.source
+}
+
+// Quiet 返回勿擾控制器
+func (e *Engine) Quiet() *quiethours.Controller {
+	return e.quiet
+}
+
+// Templates 返回通知樣板
+func (e *Engine) Templates() *render.Set {
+	return e.templates
+}
+
+// DryRunResult 代表一則通知的規則比對結果
+type DryRunResult struct {
+	Notification api.Notification
+	Decision     rules.Decision
+}
+
+// DryRun 查詢目前未通知的記錄並比對規則，但不執行任何動作
+func (e *Engine) DryRun() ([]DryRunResult, error) {
+	notifications, err := e.api().GetUnnotifiedNotifications(e.cfg.Project)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]DryRunResult, 0, len(notifications))
+	for _, n := range notifications {
+		results = append(results, DryRunResult{Notification: n, Decision: e.rules.Evaluate(n)})
+	}
+	return results, nil
+}
+
+// logError 記錄錯誤
+func (e *Engine) logError(format string, args ...interface{}) {
+	if e.logger != nil {
+		e.logger.Errorf(format, args...)
+	}
+}
+
+// logWarn 記錄警告，用於本機狀態檔儲存失敗等不影響流程的錯誤
+func (e *Engine) logWarn(format string, args ...interface{}) {
+	if e.logger != nil {
+		e.logger.Warnf(format, args...)
+	}
+}
@@ -0,0 +1,497 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"windows-notification/internal/api"
+	"windows-notification/internal/config"
+	"windows-notification/internal/ledger"
+	"windows-notification/internal/notification"
+	"windows-notification/internal/outbox"
+	"windows-notification/internal/scheduler"
+)
+
+type fakeSource struct {
+	mu        sync.Mutex
+	pending   []api.Notification
+	fetchErr  error
+	statuses  map[string]int
+	statusErr map[string]error // 依 ID 返回的狀態更新錯誤
+}
+
+func (s *fakeSource) GetUnnotifiedNotifications(project string) ([]api.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fetchErr != nil {
+		return nil, s.fetchErr
+	}
+	var out []api.Notification
+	for _, n := range s.pending {
+		if status, ok := s.statuses[n.ID]; !ok || status == api.StatusUnnotified {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeSource) SetStatus(ctx context.Context, id string, status int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.statusErr[id]; err != nil {
+		return err
+	}
+	if s.statuses == nil {
+		s.statuses = make(map[string]int)
+	}
+	s.statuses[id] = status
+	return nil
+}
+
+func (s *fakeSource) CreateNotification(req api.CreateRequest) (*api.Notification, error) {
+	return &api.Notification{}, nil
+}
+
+func (s *fakeSource) status(id string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status, ok := s.statuses[id]
+	return status, ok
+}
+
+type fakeBackend struct {
+	mu     sync.Mutex
+	titles []string
+}
+
+func (b *fakeBackend) Show(title, message string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.titles = append(b.titles, title)
+	return nil
+}
+
+func (b *fakeBackend) Get(name string) (notification.Backend, error) {
+	return b, nil
+}
+
+func (b *fakeBackend) shown() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.titles)
+}
+
+type fixedClock struct{ t time.Time }
+
+func (c fixedClock) Now() time.Time { return c.t }
+
+// newTestEngine 以暫存目錄中的本機狀態建立引擎
+func newTestEngine(t *testing.T, ackMode string, source *fakeSource) (*Engine, *fakeBackend) {
+	t.Helper()
+	return newTestEngineWith(t, &config.Config{AckMode: ackMode}, source)
+}
+
+// newTestEngineWith 以指定設定建立引擎，專案與資料目錄由測試填入
+func newTestEngineWith(t *testing.T, cfg *config.Config, source *fakeSource) (*Engine, *fakeBackend) {
+	t.Helper()
+	cfg.Project = "demo"
+	cfg.DataDir = t.TempDir()
+	backend := &fakeBackend{}
+	opts := Options{
+		Config:   cfg,
+		Source:   source,
+		Backends: backend,
+		Clock:    fixedClock{time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	OpenStores(cfg, nil).Apply(&opts)
+	e := New(opts)
+	t.Cleanup(e.Close)
+	return e, backend
+}
+
+// waitEvent 等待指定類型的事件，背景的狀態更新完成後才會發出
+func waitEvent(t *testing.T, events <-chan Event, want EventType) Event {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Type == want {
+				return ev
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s event", want)
+		}
+	}
+}
+
+func TestCheckDisplaysAndAcks(t *testing.T) {
+	source := &fakeSource{pending: []api.Notification{{ID: "1", Project: "demo", Title: "Build failed"}}}
+	e, backend := newTestEngine(t, config.AckOnDisplay, source)
+	events, unsubscribe := e.Subscribe()
+	defer unsubscribe()
+
+	e.Check()
+
+	if backend.shown() != 1 {
+		t.Fatalf("shown = %d, want 1", backend.shown())
+	}
+	if status, ok := source.status("1"); !ok || status != api.StatusNotified {
+		t.Fatalf("status = %d (%v), want %d", status, ok, api.StatusNotified)
+	}
+	if got := e.Status().Health; got != HealthOK {
+		t.Errorf("health = %s, want %s", got, HealthOK)
+	}
+
+	seen := map[EventType]bool{}
+	for len(events) > 0 {
+		seen[(<-events).Type] = true
+	}
+	for _, want := range []EventType{EventDisplayed, EventAcked, EventFetched, EventHealthChanged} {
+		if !seen[want] {
+			t.Errorf("missing %s event", want)
+		}
+	}
+
+	// 已確認的通知不會再次顯示
+	e.Check()
+	if backend.shown() != 1 {
+		t.Errorf("shown = %d after second check, want 1", backend.shown())
+	}
+}
+
+func TestManualAckWaitsForUser(t *testing.T) {
+	source := &fakeSource{pending: []api.Notification{{ID: "7", Project: "demo", Title: "Deploy"}}}
+	e, backend := newTestEngine(t, config.AckManual, source)
+
+	e.Check()
+	e.Check()
+
+	if backend.shown() != 1 {
+		t.Fatalf("shown = %d, want 1", backend.shown())
+	}
+	if _, ok := source.status("7"); ok {
+		t.Fatal("status updated before the user acknowledged")
+	}
+	if !e.ledger.Awaiting("7") {
+		t.Fatal("notification is not awaiting acknowledgement")
+	}
+
+	events, unsubscribe := e.Subscribe()
+	defer unsubscribe()
+	if err := e.Ack("7"); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+	waitEvent(t, events, EventAcked)
+	if status, _ := source.status("7"); status != api.StatusNotified {
+		t.Errorf("status = %d, want %d", status, api.StatusNotified)
+	}
+}
+
+func TestSnoozeRequiresExplicitAck(t *testing.T) {
+	cases := []struct {
+		ackMode string
+		ok      bool
+	}{
+		{config.AckOnDisplay, false},
+		{config.AckOnClick, true},
+		{config.AckManual, true},
+	}
+	for _, c := range cases {
+		source := &fakeSource{pending: []api.Notification{{ID: "3", Project: "demo", Title: "Disk full"}}}
+		e, _ := newTestEngine(t, c.ackMode, source)
+		// 延後提醒排程以系統時間判斷到期，改用系統時間避免延後的通知立即送出
+		e.clock = SystemClock{}
+		e.Check()
+
+		err := e.SnoozeByID("3", "10m")
+		if (err == nil) != c.ok || e.CanSnooze() != c.ok {
+			t.Errorf("%s: SnoozeByID() = %v, CanSnooze() = %v, want ok %v", c.ackMode, err, e.CanSnooze(), c.ok)
+		}
+		if !c.ok {
+			continue
+		}
+		// 延後期間不更新伺服器狀態，到期後仍等待使用者確認
+		if _, ok := source.status("3"); ok {
+			t.Errorf("%s: status updated while snoozed", c.ackMode)
+		}
+		if !e.ledger.Awaiting("3") || !e.scheduler.Contains("3") {
+			t.Errorf("%s: snoozed notification is not awaiting acknowledgement", c.ackMode)
+		}
+	}
+}
+
+// fakeScheduler 記錄延後的通知，並保存 Run 收到的送出函式供測試直接呼叫
+type fakeScheduler struct {
+	mu      sync.Mutex
+	items   map[string]scheduler.Item
+	deliver scheduler.DeliverFunc
+	ready   chan struct{}
+}
+
+func (s *fakeScheduler) Contains(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.items[id]
+	return ok
+}
+
+func (s *fakeScheduler) Snooze(n api.Notification, notifier string, due, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[n.ID] = scheduler.Item{Notification: n, Notifier: notifier, DueAt: due, SnoozedAt: now}
+	return nil
+}
+
+func (s *fakeScheduler) Run(ctx context.Context, deliver scheduler.DeliverFunc) {
+	s.deliver = deliver
+	close(s.ready)
+}
+
+func TestSnoozedDeliveryWaitsForCheck(t *testing.T) {
+	source := &fakeSource{pending: []api.Notification{{ID: "5", Project: "demo", Title: "Queue stuck"}}}
+	cfg := &config.Config{Project: "demo", DataDir: t.TempDir(), AckMode: config.AckManual}
+	backend := &fakeBackend{}
+	sched := &fakeScheduler{items: map[string]scheduler.Item{}, ready: make(chan struct{})}
+	opts := Options{
+		Config:   cfg,
+		Source:   source,
+		Backends: backend,
+		Clock:    fixedClock{time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	OpenStores(cfg, nil).Apply(&opts)
+	opts.Scheduler = sched
+	e := New(opts)
+	t.Cleanup(e.Close)
+	<-sched.ready
+
+	e.Check()
+	if err := e.SnoozeByID("5", "10m"); err != nil {
+		t.Fatalf("SnoozeByID: %v", err)
+	}
+
+	// 查詢進行中時到期的通知需等待查詢完成才顯示
+	e.checkMu.Lock()
+	delivered := make(chan error, 1)
+	go func() { delivered <- sched.deliver(sched.items["5"]) }()
+	select {
+	case <-delivered:
+		t.Fatal("snoozed notification delivered during a check")
+	case <-time.After(50 * time.Millisecond):
+	}
+	e.checkMu.Unlock()
+
+	if err := <-delivered; err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+	if backend.shown() != 2 || !e.ledger.Awaiting("5") {
+		t.Errorf("shown = %d, awaiting = %v, want 2 and true", backend.shown(), e.ledger.Awaiting("5"))
+	}
+}
+
+func TestAckDoesNotOverrideLaterUnread(t *testing.T) {
+	source := &fakeSource{pending: []api.Notification{{ID: "7", Project: "demo", Title: "Deploy"}}}
+	e, _ := newTestEngine(t, config.AckManual, source)
+	e.Check()
+
+	// 查詢進行中時按下確認，背景的狀態更新需等待查詢完成；在此之前已標為未讀時不再更新
+	e.checkMu.Lock()
+	if err := e.Ack("7"); err != nil {
+		e.checkMu.Unlock()
+		t.Fatalf("Ack: %v", err)
+	}
+	e.ledger.MarkReverted(api.Notification{ID: "7"}, e.clock.Now())
+	e.checkMu.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+	e.checkMu.Lock()
+	defer e.checkMu.Unlock()
+	if status, ok := source.status("7"); ok {
+		t.Errorf("status = %d, want no update after mark unread", status)
+	}
+	if !e.ledger.Reverted("7") {
+		t.Error("ledger entry is no longer reverted")
+	}
+}
+
+func TestRevertedKeptUntilServerStopsReturningIt(t *testing.T) {
+	notif := api.Notification{ID: "9", Project: "demo", Title: "Review requested"}
+	source := &fakeSource{pending: []api.Notification{notif}}
+	e, backend := newTestEngine(t, config.AckOnDisplay, source)
+	e.Check()
+	if err := e.MarkUnread(notif, false); err != nil {
+		t.Fatalf("MarkUnread: %v", err)
+	}
+
+	// 超過已確認記錄的保留時間後，伺服器仍回傳的退回通知也不會重新顯示；
+	// 同時顯示另一則通知，讓投遞記錄在之後的時間寫入
+	e.clock = fixedClock{e.clock.Now().Add(30 * 24 * time.Hour)}
+	source.mu.Lock()
+	source.pending = append(source.pending, api.Notification{ID: "10", Project: "demo", Title: "Merged"})
+	source.mu.Unlock()
+	e.Check()
+	e.Check()
+	if backend.shown() != 2 || !e.ledger.Reverted("9") {
+		t.Fatalf("shown = %d, reverted = %v, want 2 and true", backend.shown(), e.ledger.Reverted("9"))
+	}
+
+	// 伺服器不再回傳後移除記錄
+	source.mu.Lock()
+	source.pending = nil
+	source.mu.Unlock()
+	e.Check()
+	if _, ok := e.ledger.Get("9"); ok {
+		t.Error("reverted entry kept after the server stopped returning it")
+	}
+}
+
+func TestFetchErrorReportsHealthDown(t *testing.T) {
+	fetchErr := errors.New("connection refused")
+	source := &fakeSource{fetchErr: fetchErr}
+	e, _ := newTestEngine(t, config.AckOnDisplay, source)
+	events, unsubscribe := e.Subscribe()
+	defer unsubscribe()
+
+	e.Check()
+
+	status := e.Status()
+	if status.Health != HealthDown || status.LastError != fetchErr.Error() {
+		t.Fatalf("status = %+v, want health down with the fetch error", status)
+	}
+
+	var gotHealth, gotError bool
+	for len(events) > 0 {
+		ev := <-events
+		switch ev.Type {
+		case EventHealthChanged:
+			gotHealth = ev.Health == HealthDown
+		case EventError:
+			gotError = errors.Is(ev.Err, fetchErr)
+		}
+	}
+	if !gotHealth || !gotError {
+		t.Errorf("health_changed = %v, error = %v, want both", gotHealth, gotError)
+	}
+}
+
+func TestProfileLabelPrefixesTitle(t *testing.T) {
+	source := &fakeSource{pending: []api.Notification{{ID: "3", Project: "demo", Title: "Disk full"}}}
+	e, backend := newTestEngine(t, config.AckOnDisplay, source)
+	e.profile = "staging"
+
+	e.Check()
+
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	if len(backend.titles) != 1 || backend.titles[0] != "[staging] Disk full" {
+		t.Errorf("titles = %q, want the profile name before the title", backend.titles)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	interval := 10 * time.Second
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 10 * time.Second},
+		{1, 20 * time.Second},
+		{3, 80 * time.Second},
+		{10, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := backoff(interval, tt.failures); got != tt.want {
+			t.Errorf("backoff(%s, %d) = %s, want %s", interval, tt.failures, got, tt.want)
+		}
+	}
+
+	// 間隔本身超過上限時不縮短
+	if got := backoff(10*time.Minute, 2); got != 10*time.Minute {
+		t.Errorf("backoff(10m, 2) = %s, want 10m", got)
+	}
+}
+
+func TestQueuedAckIsNotProcessedAgain(t *testing.T) {
+	var forwards int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&forwards, 1)
+	}))
+	defer server.Close()
+
+	// 1 的狀態更新持續失敗並留在 outbox，2 的狀態更新因此排在後面
+	source := &fakeSource{
+		pending: []api.Notification{
+			{ID: "1", Project: "demo", Title: "Build failed"},
+			{ID: "2", Project: "demo", Title: "Nightly report"},
+		},
+		statusErr: map[string]error{"1": errors.New("connection reset")},
+	}
+	e, backend := newTestEngineWith(t, &config.Config{
+		AckMode: config.AckOnDisplay,
+		Rules: []config.Rule{{
+			Match:  config.RuleMatch{Title: "Nightly"},
+			Action: config.RuleAction{Type: "forward", URL: server.URL},
+		}},
+	}, source)
+
+	e.Check()
+	e.Check()
+	e.Check()
+
+	if n := atomic.LoadInt32(&forwards); n != 1 {
+		t.Errorf("forwarded %d times, want 1", n)
+	}
+	if backend.shown() != 1 {
+		t.Errorf("shown = %d, want 1", backend.shown())
+	}
+	if entry, ok := e.ledger.Get("2"); !ok || entry.State != ledger.StateAckPending {
+		t.Errorf("ledger entry = %+v, want ack_pending", entry)
+	}
+	if e.outbox.Len() != 2 {
+		t.Errorf("outbox = %d, want 2", e.outbox.Len())
+	}
+}
+
+func TestOutboxRejectedItemDoesNotBlockQueue(t *testing.T) {
+	source := &fakeSource{statusErr: map[string]error{
+		"1": &api.HTTPError{StatusCode: 404},
+		"4": &api.HTTPError{StatusCode: 503},
+	}}
+	e, _ := newTestEngine(t, config.AckOnDisplay, source)
+	queue := e.outbox.(*outbox.Outbox)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, id := range []string{"1", "2", "3"} {
+		queue.Enqueue(api.Notification{ID: id}, api.StatusNotified, now)
+	}
+
+	// 404 的更新移出佇列，不阻擋後面的更新
+	e.FlushOutbox(true)
+	for _, id := range []string{"2", "3"} {
+		if _, ok := source.status(id); !ok {
+			t.Errorf("status for %s not sent", id)
+		}
+	}
+	if queue.Len() != 0 {
+		t.Errorf("queue length = %d, want 0", queue.Len())
+	}
+	if rejected := queue.Rejected(); len(rejected) != 1 || rejected[0].Notification.ID != "1" {
+		t.Errorf("rejected = %+v, want item 1", rejected)
+	}
+
+	// 5xx 仍保留在佇列前端，後面的更新等待重試以保持順序
+	queue.Enqueue(api.Notification{ID: "4"}, api.StatusNotified, now)
+	queue.Enqueue(api.Notification{ID: "5"}, api.StatusNotified, now)
+	e.FlushOutbox(true)
+	if _, ok := source.status("5"); ok {
+		t.Error("status for 5 sent before 4")
+	}
+	if queue.Len() != 2 {
+		t.Errorf("queue length = %d, want 2", queue.Len())
+	}
+}
@@ -0,0 +1,473 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+
+	"windows-notification/internal/api"
+	"windows-notification/internal/dedup"
+	"windows-notification/internal/history"
+	"windows-notification/internal/notification"
+	"windows-notification/internal/outbox"
+	"windows-notification/internal/quiethours"
+	"windows-notification/internal/ratelimit"
+	"windows-notification/internal/rules"
+	"windows-notification/internal/scheduler"
+)
+
+// Check 查詢一次未通知的記錄並依規則、勿擾、去重與限流處理
+func (e *Engine) Check() {
+	e.checkMu.Lock()
+	defer e.checkMu.Unlock()
+
+	e.escalate()
+	e.flushQuietDigest()
+	e.releaseQueue()
+
+	notifications, err := e.api().GetUnnotifiedNotifications(e.cfg.Project)
+	if err != nil {
+		e.logError("API 查詢失敗: %v", err)
+		e.setHealth(HealthDown, err)
+		e.emit(Event{Type: EventError, Err: err})
+		return
+	}
+
+	e.pruneReverted(notifications)
+
+	// 查詢成功代表伺服器可連線，先依序送出離線期間保存的狀態更新
+	e.flushOutbox(false)
+	defer func() {
+		e.flushOutbox(false)
+		if e.outbox != nil && e.outbox.Len() > 0 {
+			e.setHealth(HealthDegraded, nil)
+		} else {
+			e.setHealth(HealthOK, nil)
+		}
+		e.emit(Event{Type: EventFetched, Count: len(notifications)})
+	}()
+
+	if len(notifications) == 0 {
+		if e.logger != nil {
+			e.logger.Debug("沒有未通知的記錄")
+		}
+		return
+	}
+
+	if e.logger != nil {
+		e.logger.Infof("發現 %d 個未通知的記錄", len(notifications))
+	}
+
+	quiet := e.quiet.Active(e.clock.Now())
+
+	for _, notif := range notifications {
+		e.recordReceived(notif)
+
+		// 本機標為未讀的通知留給其他客戶端處理，不再顯示也不更新狀態
+		if e.ledger != nil && e.ledger.Reverted(notif.ID) {
+			continue
+		}
+
+		// 等待使用者確認的通知不再顯示，也不自動更新狀態
+		if e.ledger != nil && e.ledger.Awaiting(notif.ID) {
+			continue
+		}
+
+		// 已顯示過的通知只補送狀態更新，不再顯示
+		if e.ledger != nil && e.ledger.Delivered(notif.ID) {
+			if e.outbox == nil || !e.outbox.Contains(notif.ID) {
+				e.markNotified(notif, "已補送狀態更新")
+			}
+			continue
+		}
+
+		// 延後中的通知在到期前不顯示也不更新狀態
+		if e.scheduler != nil && e.scheduler.Contains(notif.ID) {
+			continue
+		}
+
+		// 已隔離的通知不再重試，需由使用者在隔離清單中處理
+		if e.quarantine != nil && e.quarantine.Contains(notif.ID) {
+			continue
+		}
+
+		// 已在限流佇列中的通知由 releaseQueue 處理
+		if e.limiter != nil && e.limiter.Queued(notif.ID) {
+			continue
+		}
+
+		decision := e.rules.Evaluate(notif)
+		notif = decision.Notification
+
+		switch decision.Action {
+		case rules.ActionSuppress:
+			if e.logger != nil {
+				e.logger.Debugf("規則略過通知 (ID: %s): %s", notif.ID, decision.Describe())
+			}
+			e.recordOutcome(notif, history.OutcomeSuppressed)
+			continue
+		case rules.ActionAck:
+			e.recordOutcome(notif, history.OutcomeSilenced)
+			e.markNotified(notif, "已靜默確認")
+			continue
+		case rules.ActionForward:
+			if err := notification.PostJSON(nil, decision.ForwardURL, notif); err != nil {
+				e.logError("轉送通知失敗 (ID: %s): %v", notif.ID, err)
+				e.emit(Event{Type: EventError, Notification: notif, Err: err})
+				continue
+			}
+			e.recordOutcome(notif, history.OutcomeForwarded)
+			e.markNotified(notif, "已轉送")
+			continue
+		}
+
+		// 勿擾期間保留非緊急通知，不更新狀態
+		if quiet && !e.quiet.Breaks(notif) {
+			if e.quiet.Hold(notif) && e.logger != nil {
+				e.logger.Infof("勿擾中，已保留通知 (ID: %s): %s", notif.ID, notif.Title)
+			}
+			continue
+		}
+
+		if e.dedup != nil {
+			duplicate, err := e.dedup.IsDuplicate(notif, e.clock.Now())
+			if err != nil {
+				e.logWarn("儲存去重狀態失敗: %v", err)
+			}
+			if duplicate {
+				e.recordOutcome(notif, history.OutcomeDuplicate)
+				e.markNotified(notif, "重複通知已合併")
+				continue
+			}
+		}
+
+		// 超過顯示上限的通知排隊稍後顯示，不更新狀態
+		if e.limiter != nil && !e.limiter.Allow(notif.Project, e.clock.Now()) {
+			if e.limiter.Enqueue(ratelimit.Item{Notification: notif, Notifier: decision.Notifier}) && e.logger != nil {
+				e.logger.Infof("已達顯示上限，通知加入佇列 (ID: %s)", notif.ID)
+			}
+			continue
+		}
+
+		e.display(notif, decision.Notifier)
+	}
+}
+
+// flushQuietDigest 勿擾結束後以摘要通知呈現保留的通知，並更新其狀態
+func (e *Engine) flushQuietDigest() {
+	if e.quiet.HeldCount() == 0 || e.quiet.Active(e.clock.Now()) {
+		return
+	}
+
+	held := e.quiet.Release()
+	title, message := quiethours.Digest(held, digestMaxLines)
+	if err := e.showSummary(title, message); err != nil {
+		e.logError("顯示勿擾摘要失敗: %v", err)
+		// 摘要未顯示則不更新狀態，下次查詢時重新保留
+		return
+	}
+
+	if e.logger != nil {
+		e.logger.Infof("勿擾結束，已顯示 %d 則通知的摘要", len(held))
+	}
+
+	for _, notif := range held {
+		e.recordOutcome(notif, history.OutcomeSummarized)
+		e.markNotified(notif, "已通知（摘要）")
+	}
+}
+
+// pruneReverted 移除伺服器已不再回傳的已退回記錄；退回的狀態更新仍在 outbox 中時伺服器尚未收到，需保留
+func (e *Engine) pruneReverted(notifications []api.Notification) {
+	if e.ledger == nil {
+		return
+	}
+	unnotified := make(map[string]bool, len(notifications))
+	for _, n := range notifications {
+		unnotified[n.ID] = true
+	}
+	err := e.ledger.PruneReverted(func(id string) bool {
+		return unnotified[id] || (e.outbox != nil && e.outbox.Contains(id))
+	}, e.clock.Now())
+	if err != nil {
+		e.logWarn("儲存投遞記錄失敗: %v", err)
+	}
+}
+
+// deliverSnoozed 顯示到期的延後通知，供延後提醒排程呼叫；與 Check 使用同一把鎖，
+// 避免同時查詢到同一則通知時重複顯示或交錯更新本機狀態
+func (e *Engine) deliverSnoozed(item scheduler.Item) error {
+	e.checkMu.Lock()
+	defer e.checkMu.Unlock()
+
+	if e.logger != nil {
+		e.logger.Infof("延後的通知已到期 (ID: %s): %s", item.Notification.ID, item.Notification.Title)
+	}
+	return e.display(item.Notification, item.Notifier)
+}
+
+// releaseQueue 顯示限流佇列中已可顯示的通知；佇列過長時合併為摘要
+func (e *Engine) releaseQueue() {
+	if e.limiter == nil {
+		return
+	}
+
+	if items := e.limiter.Overflow(); len(items) > 0 {
+		title, message := ratelimit.Summary(items)
+		if err := e.showSummary(title, message); err != nil {
+			e.logError("顯示合併摘要失敗: %v", err)
+			// 摘要未顯示則不更新狀態，下次查詢時重新排隊
+			return
+		}
+		e.logWarn("佇列過長，已合併 %d 則通知為摘要", len(items))
+		for _, item := range items {
+			e.recordOutcome(item.Notification, history.OutcomeSummarized)
+			e.markNotified(item.Notification, "已通知（合併）")
+		}
+		return
+	}
+
+	for _, item := range e.limiter.Release(e.clock.Now()) {
+		e.display(item.Notification, item.Notifier)
+	}
+}
+
+// showSummary 以預設後端顯示摘要通知
+func (e *Engine) showSummary(title, message string) error {
+	backend, err := e.backends.Get("")
+	if err != nil {
+		return err
+	}
+	return backend.Show(e.profileLabel()+title, message)
+}
+
+// display 以指定後端顯示通知並更新狀態，返回顯示錯誤
+func (e *Engine) display(notif api.Notification, notifier string) error {
+	backend, err := e.backends.Get(notifier)
+	if err != nil {
+		e.displayFailed(notif, err)
+		return err
+	}
+
+	title, message, err := e.templates.Render(notif)
+	if err != nil {
+		e.logWarn("套用樣板失敗，使用原始內容 (ID: %s): %v", notif.ID, err)
+	}
+	if e.dedup != nil {
+		title = dedup.Title(title, e.dedup.Pending(notif))
+	}
+
+	if err := notification.ShowWith(backend, e.profileLabel()+title, message, e.toastOptions(notif, false)); err != nil {
+		e.displayFailed(notif, err)
+		return err
+	}
+
+	now := e.clock.Now()
+	if e.quarantine != nil {
+		if err := e.quarantine.RecordSuccess(notif.ID); err != nil {
+			e.logWarn("儲存隔離清單失敗: %v", err)
+		}
+	}
+
+	e.recordOutcome(notif, history.OutcomeDisplayed)
+	if e.ledger != nil {
+		if err := e.ledger.MarkShown(notif, now); err != nil {
+			e.logWarn("儲存投遞記錄失敗: %v", err)
+		}
+	}
+	if e.dedup != nil {
+		if err := e.dedup.Shown(notif, now); err != nil {
+			e.logWarn("儲存去重狀態失敗: %v", err)
+		}
+	}
+	e.emit(Event{Type: EventDisplayed, Notification: notif})
+
+	// 明確確認模式下等待使用者確認後才更新狀態
+	if e.cfg.ExplicitAck() && e.ledger != nil {
+		if err := e.ledger.MarkAwaiting(notif.ID, now); err != nil {
+			e.logWarn("儲存投遞記錄失敗: %v", err)
+		}
+		if e.logger != nil {
+			e.logger.Infof("已顯示，等待確認 (ID: %s): %s", notif.ID, notif.Title)
+		}
+		return nil
+	}
+
+	e.markNotified(notif, "已通知")
+	return nil
+}
+
+// displayFailed 記錄顯示失敗，連續失敗達門檻時隔離該通知
+func (e *Engine) displayFailed(notif api.Notification, showErr error) {
+	e.logError("顯示通知失敗 (ID: %s): %v", notif.ID, showErr)
+	e.emit(Event{Type: EventError, Notification: notif, Err: showErr})
+
+	quarantined := false
+	if e.quarantine != nil {
+		var err error
+		quarantined, err = e.quarantine.RecordFailure(notif, showErr, e.clock.Now())
+		if err != nil {
+			e.logWarn("儲存隔離清單失敗: %v", err)
+		}
+	}
+	if e.history != nil {
+		if err := e.history.RecordError(notif, showErr, quarantined, e.clock.Now()); err != nil {
+			e.logWarn("儲存通知歷史失敗: %v", err)
+		}
+	}
+	if !quarantined {
+		return
+	}
+
+	e.logWarn("通知多次顯示失敗，已隔離 (ID: %s): %s", notif.ID, notif.Title)
+	if e.cfg.Quarantine.Report {
+		go e.reportQuarantine(notif, showErr)
+	}
+}
+
+// reportQuarantine 建立一則通知回報伺服器有通知被隔離
+func (e *Engine) reportQuarantine(notif api.Notification, showErr error) {
+	project := e.cfg.Quarantine.ReportProject
+	if project == "" {
+		project = "notification-client"
+	}
+
+	_, err := e.api().CreateNotification(api.CreateRequest{
+		Project: project,
+		Title:   "通知顯示失敗已隔離",
+		Message: fmt.Sprintf("通知 #%s（%s / %s）多次顯示失敗: %v", notif.ID, notif.Project, notif.Title, showErr),
+	})
+	if err != nil {
+		e.logError("回報隔離通知失敗 (ID: %s): %v", notif.ID, err)
+	}
+}
+
+// FlushOutbox 依序重送離線期間保存的狀態更新；force 時忽略退避時間
+func (e *Engine) FlushOutbox(force bool) {
+	e.checkMu.Lock()
+	defer e.checkMu.Unlock()
+	e.flushOutbox(force)
+}
+
+// flushOutbox 是 FlushOutbox 的實作（呼叫端需持有 checkMu）
+func (e *Engine) flushOutbox(force bool) {
+	if e.outbox == nil {
+		return
+	}
+
+	sent, err := e.outbox.Flush(e.clock.Now(), force, func(item outbox.Item) error {
+		if err := e.api().SetStatus(context.Background(), item.Notification.ID, item.Status); err != nil {
+			// 例如通知已被刪除，重送也不會成功，移出佇列以免阻擋後面的更新
+			if api.IsPermanent(err) {
+				e.logError("狀態更新被伺服器拒絕，不再重送 (ID: %s): %v", item.Notification.ID, err)
+				e.emit(Event{Type: EventError, Notification: item.Notification, Err: err})
+				return outbox.Reject(err)
+			}
+			return err
+		}
+		if item.Status == api.StatusUnnotified {
+			e.recordReverted(item.Notification)
+		} else {
+			e.recordAcked(item.Notification, "已補送狀態更新")
+		}
+		return nil
+	})
+	if err != nil {
+		e.logWarn("重送狀態更新失敗，剩餘 %d 筆稍後重試: %v", e.outbox.Len(), err)
+	}
+	if sent > 0 && e.logger != nil {
+		e.logger.Infof("已重送 %d 筆狀態更新", sent)
+	}
+}
+
+// MarkNotified 更新通知狀態為已通知，結果記錄在投遞記錄中；
+// 暫時性的失敗或 outbox 中仍有較早的更新時改為排入 outbox，以保持順序
+func (e *Engine) MarkNotified(notif api.Notification, label string) {
+	e.checkMu.Lock()
+	defer e.checkMu.Unlock()
+	e.markNotified(notif, label)
+}
+
+// markNotified 是 MarkNotified 的實作（呼叫端需持有 checkMu）
+func (e *Engine) markNotified(notif api.Notification, label string) {
+	if e.outbox != nil && e.outbox.Len() > 0 {
+		e.deferAck(notif, nil)
+		return
+	}
+
+	if err := e.api().SetStatus(context.Background(), notif.ID, api.StatusNotified); err != nil {
+		e.logError("更新狀態失敗 (ID: %s): %v", notif.ID, err)
+		e.emit(Event{Type: EventError, Notification: notif, Err: err})
+		if api.IsPermanent(err) {
+			// 重送也不會成功，只記錄錯誤；伺服器再回傳時會重新補送
+			if e.ledger != nil {
+				if err := e.ledger.MarkAckPending(notif, e.clock.Now(), err); err != nil {
+					e.logWarn("儲存投遞記錄失敗: %v", err)
+				}
+			}
+			return
+		}
+		e.deferAck(notif, err)
+		return
+	}
+
+	e.recordAcked(notif, label)
+}
+
+// deferAck 將狀態更新排入 outbox 稍後重送；同時記錄在投遞記錄中，
+// 重送前伺服器再回傳同一則通知時只補送狀態，不會再次顯示、轉送或合併
+func (e *Engine) deferAck(notif api.Notification, ackErr error) {
+	if e.ledger != nil {
+		if err := e.ledger.MarkAckPending(notif, e.clock.Now(), ackErr); err != nil {
+			e.logWarn("儲存投遞記錄失敗: %v", err)
+		}
+	}
+	if e.outbox != nil {
+		if err := e.outbox.Enqueue(notif, api.StatusNotified, e.clock.Now()); err != nil {
+			e.logWarn("儲存狀態更新佇列失敗: %v", err)
+		}
+	}
+}
+
+// recordAcked 記錄伺服器狀態已更新
+func (e *Engine) recordAcked(notif api.Notification, label string) {
+	now := e.clock.Now()
+	if e.ledger != nil {
+		if err := e.ledger.MarkAcked(notif, now); err != nil {
+			e.logWarn("儲存投遞記錄失敗: %v", err)
+		}
+	}
+	if e.history != nil {
+		if err := e.history.MarkAcked(notif.ID, now); err != nil {
+			e.logWarn("儲存通知歷史失敗: %v", err)
+		}
+	}
+	if e.logger != nil {
+		e.logger.Successf("%s: %s - %s", label, notif.Title, notif.Message)
+	}
+	e.emit(Event{Type: EventAcked, Notification: notif})
+}
+
+// recordReceived 記錄收到的通知
+func (e *Engine) recordReceived(notif api.Notification) {
+	if e.history == nil {
+		return
+	}
+	added, err := e.history.Received(notif, e.clock.Now())
+	if err != nil {
+		e.logWarn("儲存通知歷史失敗: %v", err)
+	}
+	if added {
+		e.emit(Event{Type: EventUpdated, Notification: notif})
+	}
+}
+
+// recordOutcome 記錄通知的處理結果
+func (e *Engine) recordOutcome(notif api.Notification, outcome history.Outcome) {
+	if e.history == nil {
+		return
+	}
+	if err := e.history.SetOutcome(notif, outcome, e.clock.Now()); err != nil {
+		e.logWarn("儲存通知歷史失敗: %v", err)
+	}
+	e.emit(Event{Type: EventUpdated, Notification: notif})
+}
@@ -0,0 +1,100 @@
+package monitor
+
+import (
+	"windows-notification/internal/config"
+	"windows-notification/internal/dedup"
+	"windows-notification/internal/history"
+	"windows-notification/internal/ledger"
+	"windows-notification/internal/logger"
+	"windows-notification/internal/outbox"
+	"windows-notification/internal/quarantine"
+	"windows-notification/internal/scheduler"
+)
+
+// Stores 是保存在資料目錄中的本機狀態；無法載入的項目為 nil
+type Stores struct {
+	Ledger     *ledger.Ledger
+	Outbox     *outbox.Outbox
+	History    *history.Store
+	Quarantine *quarantine.Store
+	Scheduler  *scheduler.Scheduler
+	Dedup      *dedup.Deduper
+}
+
+// OpenStores 載入資料目錄中的本機狀態，載入失敗時記錄錯誤並停用對應功能
+func OpenStores(cfg *config.Config, log *logger.Logger) Stores {
+	var s Stores
+	var err error
+
+	logError := func(format string, args ...interface{}) {
+		if log != nil {
+			log.Errorf(format, args...)
+		}
+	}
+
+	// 投遞記錄確保已顯示的通知不會因狀態更新失敗而重複顯示
+	s.Ledger, err = ledger.Open(cfg.DataPath("ledger.json"))
+	if err != nil {
+		logError("無法載入投遞記錄，重複顯示保護已停用: %v", err)
+		s.Ledger = nil
+	}
+
+	// 離線時的狀態更新保存在 outbox，恢復連線後依序重送
+	s.Outbox, err = outbox.Open(cfg.DataPath("outbox.json"))
+	if err != nil {
+		logError("無法載入狀態更新佇列，離線更新將不會保存: %v", err)
+		s.Outbox = nil
+	}
+
+	// 持續顯示失敗的通知會被隔離，避免每次查詢都重試
+	s.Quarantine, err = quarantine.Open(cfg.DataPath("quarantine.json"), cfg.Quarantine.Threshold)
+	if err != nil {
+		logError("無法載入隔離清單，顯示失敗隔離已停用: %v", err)
+		s.Quarantine = nil
+	}
+
+	// 通知歷史保存收到的通知與處理結果
+	s.History, err = history.Open(cfg.DataPath("history.json"), cfg.History)
+	if err != nil {
+		logError("無法載入通知歷史，歷史記錄已停用: %v", err)
+		s.History = nil
+	}
+
+	s.Scheduler, err = scheduler.Open(cfg.DataPath("snoozed.json"), log)
+	if err != nil {
+		logError("無法載入延後提醒清單，延後功能已停用: %v", err)
+		s.Scheduler = nil
+	}
+
+	if cfg.Dedup.Enabled {
+		s.Dedup, err = dedup.New(cfg.DataPath("dedup.json"), cfg.Dedup)
+		if err != nil {
+			logError("重複通知合併設定無效，已停用: %v", err)
+			s.Dedup = nil
+		}
+	}
+	return s
+}
+
+// Apply 將已載入的本機狀態填入引擎選項；nil 的項目不填入，
+// 避免介面持有 nil 指標而無法判斷為停用
+func (s Stores) Apply(opts *Options) {
+	if s.Ledger != nil {
+		opts.Ledger = s.Ledger
+	}
+	if s.Outbox != nil {
+		opts.Outbox = s.Outbox
+	}
+	if s.History != nil {
+		opts.History = s.History
+	}
+	if s.Quarantine != nil {
+		opts.Quarantine = s.Quarantine
+	}
+	if s.Scheduler != nil {
+		opts.Scheduler = s.Scheduler
+	}
+	if s.Dedup != nil {
+		opts.Dedup = s.Dedup
+	}
+}
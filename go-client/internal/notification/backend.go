@@ -0,0 +1,194 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"windows-notification/internal/config"
+	"windows-notification/internal/logger"
+	"windows-notification/internal/sanitize"
+)
+
+// commandLimits 避免超過 Windows 命令列長度上限
+var commandLimits = sanitize.Limits{Title: 256, Message: 8000}
+
+// DefaultBackend 是預設通知後端（Windows 系統通知）的名稱
+const DefaultBackend = "toast"
+
+// Backend 是可顯示通知的後端
+type Backend interface {
+	Show(title, message string) error
+}
+
+// Action 代表通知上的動作按鈕，點擊後開啟 URL
+type Action struct {
+	Label string
+	URL   string
+}
+
+// Options 代表通知的進階顯示選項
+type Options struct {
+	Actions   []Action // 動作按鈕
+	LaunchURL string   // 點擊通知本身時開啟的網址
+	Urgent    bool     // 以強調樣式顯示（持續音效、較長顯示時間）
+}
+
+// RichBackend 是支援動作按鈕與強調樣式的後端
+type RichBackend interface {
+	Backend
+	ShowRich(title, message string, opts Options) error
+}
+
+// ShowWith 以進階選項顯示通知；後端不支援時退回一般顯示
+func ShowWith(b Backend, title, message string, opts Options) error {
+	if rich, ok := b.(RichBackend); ok {
+		return rich.ShowRich(title, message, opts)
+	}
+	if opts.Urgent {
+		title = "‼ " + title
+	}
+	return b.Show(title, message)
+}
+
+// Console 將通知輸出到標準輸出
+type Console struct{}
+
+// Show 將通知輸出到標準輸出
+func (Console) Show(title, message string) error {
+	title, message = sanitize.Prepare(title, message, sanitize.Limits{})
+	_, err := fmt.Fprintf(os.Stdout, "[%s] %s\n%s\n", time.Now().Format("15:04:05"), title, message)
+	return err
+}
+
+// Webhook 以 HTTP POST 將通知送到指定網址
+type Webhook struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+// Show 將通知以 JSON 送到 webhook
+func (w *Webhook) Show(title, message string) error {
+	return PostJSON(w.HTTPClient, w.URL, map[string]string{
+		"title":   sanitize.StripControl(title),
+		"message": sanitize.StripControl(message),
+	})
+}
+
+// Command 執行外部指令，標題與內容附加為最後兩個參數
+type Command struct {
+	Args []string
+}
+
+// Show 執行外部指令
+func (c *Command) Show(title, message string) error {
+	if len(c.Args) == 0 {
+		return fmt.Errorf("未設定指令")
+	}
+	title, message = sanitize.Prepare(title, message, commandLimits)
+	args := append(append([]string{}, c.Args[1:]...), title, message)
+	if out, err := exec.Command(c.Args[0], args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("執行指令失敗: %w (%s)", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// PostJSON 以 HTTP POST 送出 JSON，非 2xx 回應視為失敗
+func PostJSON(client *http.Client, url string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("建立請求失敗: %w", err)
+	}
+
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("請求失敗: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("回應錯誤: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Registry 依名稱管理通知後端
+type Registry struct {
+	backends map[string]Backend
+}
+
+// NewRegistry 依設定建立後端清單，預設後端固定為 toast
+func NewRegistry(toast Backend, cfgs map[string]config.NotifierConfig, log *logger.Logger) (*Registry, error) {
+	r := &Registry{backends: map[string]Backend{DefaultBackend: toast}}
+
+	for name, cfg := range cfgs {
+		backend, err := newBackend(cfg, toast)
+		if err != nil {
+			return nil, fmt.Errorf("通知後端 %q: %w", name, err)
+		}
+		r.backends[name] = backend
+		if log != nil {
+			log.Debugf("已載入通知後端: %s (%s)", name, cfg.Type)
+		}
+	}
+
+	return r, nil
+}
+
+// newBackend 依類型建立單一後端
+func newBackend(cfg config.NotifierConfig, toast Backend) (Backend, error) {
+	switch cfg.Type {
+	case "toast":
+		return toast, nil
+	case "console":
+		return Console{}, nil
+	case "webhook":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("webhook 需要 url")
+		}
+		return &Webhook{URL: cfg.URL, HTTPClient: &http.Client{Timeout: 10 * time.Second}}, nil
+	case "command":
+		if len(cfg.Command) == 0 {
+			return nil, fmt.Errorf("command 需要 command")
+		}
+		return &Command{Args: cfg.Command}, nil
+	default:
+		return nil, fmt.Errorf("未知的類型 %q", cfg.Type)
+	}
+}
+
+// WithDefault 返回以 name 後端取代預設後端的清單，name 為空時返回原清單
+func (r *Registry) WithDefault(name string) (*Registry, error) {
+	if name == "" {
+		return r, nil
+	}
+	backend, err := r.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	backends := make(map[string]Backend, len(r.backends))
+	for n, b := range r.backends {
+		backends[n] = b
+	}
+	backends[DefaultBackend] = backend
+	return &Registry{backends: backends}, nil
+}
+
+// Get 依名稱取得後端，空字串返回預設後端
+func (r *Registry) Get(name string) (Backend, error) {
+	if name == "" {
+		name = DefaultBackend
+	}
+	backend, ok := r.backends[name]
+	if !ok {
+		return nil, fmt.Errorf("找不到通知後端 %q", name)
+	}
+	return backend, nil
+}
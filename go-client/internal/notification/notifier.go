@@ -3,10 +3,13 @@ package notification
 import (
 	"fmt"
 
-	"github.com/go-toast/toast"
 	"windows-notification/internal/logger"
+	"windows-notification/internal/sanitize"
 )
 
+// ToastLimits 是 Windows 系統通知可完整顯示的大致字元數
+var ToastLimits = sanitize.Limits{Title: 64, Message: 250}
+
 // Notifier 負責顯示系統通知
 type Notifier struct {
 	AppID  string
@@ -23,17 +26,19 @@ func NewNotifier(appID string, log *logger.Logger) *Notifier {
 
 // Show 顯示 Windows 系統通知
 func (n *Notifier) Show(title, message string) error {
+	return n.ShowRich(title, message, Options{})
+}
+
+// ShowRich 顯示帶有動作按鈕或強調樣式的 Windows 系統通知
+func (n *Notifier) ShowRich(title, message string, opts Options) error {
 	if n.Logger != nil {
 		n.Logger.Debugf("準備顯示通知: 標題='%s', 訊息='%s'", title, message)
 	}
 
-	notification := toast.Notification{
-		AppID:   n.AppID,
-		Title:   title,
-		Message: message,
-	}
+	// 轉為純文字並跳脫，避免特殊字元破壞 go-toast 產生的 XML 與 PowerShell 腳本
+	title, message = sanitize.Prepare(title, message, ToastLimits)
 
-	err := notification.Push()
+	err := n.push(title, message, opts)
 	if err != nil {
 		if n.Logger != nil {
 			n.Logger.Errorf("顯示 Windows 通知失敗: %v (標題: %s)", err, title)
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package notification
+
+import "errors"
+
+// push 在非 Windows 平台無法顯示系統通知
+func (n *Notifier) push(title, message string, opts Options) error {
+	return errors.New("Windows 系統通知僅支援 Windows")
+}
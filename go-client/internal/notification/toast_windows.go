@@ -0,0 +1,34 @@
+//go:build windows
+
+package notification
+
+import (
+	"github.com/go-toast/toast"
+	"windows-notification/internal/sanitize"
+)
+
+// push 以 go-toast 推送已清理的通知
+func (n *Notifier) push(title, message string, opts Options) error {
+	notification := toast.Notification{
+		AppID:   n.AppID,
+		Title:   sanitize.EscapeToast(title),
+		Message: sanitize.EscapeToast(message),
+	}
+	if opts.LaunchURL != "" {
+		notification.ActivationArguments = sanitize.EscapeToastAttr(opts.LaunchURL)
+	}
+	for _, action := range opts.Actions {
+		notification.Actions = append(notification.Actions, toast.Action{
+			Type:      "protocol",
+			Label:     sanitize.EscapeToastAttr(action.Label),
+			Arguments: sanitize.EscapeToastAttr(action.URL),
+		})
+	}
+	if opts.Urgent {
+		notification.Audio = toast.LoopingAlarm
+		notification.Loop = true
+		notification.Duration = toast.Long
+	}
+
+	return notification.Push()
+}
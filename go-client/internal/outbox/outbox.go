@@ -0,0 +1,216 @@
+package outbox
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"windows-notification/internal/api"
+	"windows-notification/internal/jsonfile"
+)
+
+// 重送退避時間
+const (
+	minBackoff = 5 * time.Second
+	maxBackoff = 5 * time.Minute
+)
+
+// maxRejected 是保留的無法送出記錄筆數上限，超過時移除最舊的
+const maxRejected = 200
+
+// Item 代表一筆待送出的狀態更新
+type Item struct {
+	Notification api.Notification `json:"notification"`
+	Status       int              `json:"status"`
+	QueuedAt     time.Time        `json:"queued_at"`
+	Attempts     int              `json:"attempts"`
+	NextAttempt  time.Time        `json:"next_attempt"`
+	LastError    string           `json:"last_error,omitempty"`
+}
+
+// SendFunc 送出一筆狀態更新；重送也不會成功時以 Reject 包裝錯誤
+type SendFunc func(item Item) error
+
+// rejectError 代表重送也不會成功的錯誤
+type rejectError struct {
+	err error
+}
+
+func (e *rejectError) Error() string { return e.err.Error() }
+func (e *rejectError) Unwrap() error { return e.err }
+
+// Reject 包裝重送也不會成功的錯誤（例如通知已被刪除）；SendFunc 返回時該筆移出佇列，
+// 保存在無法送出的記錄中，不阻擋後面的更新
+func Reject(err error) error {
+	return &rejectError{err: err}
+}
+
+// Outbox 是持久化的狀態更新佇列，伺服器無法連線時保存更新並依序重送
+type Outbox struct {
+	flushMu      sync.Mutex // 同一時間只執行一次 Flush
+	mu           sync.Mutex
+	path         string
+	items        []Item
+	rejectedPath string
+	rejected     []Item
+}
+
+// Open 開啟（或建立）佇列；無法送出的記錄存放在同目錄的 <名稱>-rejected.json
+func Open(path string) (*Outbox, error) {
+	o := &Outbox{
+		path:         path,
+		rejectedPath: strings.TrimSuffix(path, filepath.Ext(path)) + "-rejected.json",
+	}
+	if err := jsonfile.Load(path, &o.items); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err := jsonfile.Load(o.rejectedPath, &o.rejected); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Enqueue 加入一筆狀態更新；同一則通知已在佇列中時只更新目標狀態
+func (o *Outbox) Enqueue(n api.Notification, status int, now time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for i := range o.items {
+		if o.items[i].Notification.ID == n.ID {
+			o.items[i].Status = status
+			return o.save()
+		}
+	}
+
+	o.items = append(o.items, Item{Notification: n, Status: status, QueuedAt: now})
+	return o.save()
+}
+
+// Contains 判斷通知是否已在佇列中
+func (o *Outbox) Contains(id string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, item := range o.items {
+		if item.Notification.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Len 返回佇列長度
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.items)
+}
+
+// Rejected 返回無法送出而移出佇列的記錄，由舊到新排序
+func (o *Outbox) Rejected() []Item {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]Item(nil), o.rejected...)
+}
+
+// Oldest 返回最舊一筆的加入時間，佇列為空時為零值
+func (o *Outbox) Oldest() time.Time {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.items) == 0 {
+		return time.Time{}
+	}
+	return o.items[0].QueuedAt
+}
+
+// Flush 依加入順序送出狀態更新，遇到暫時性的失敗即停止並延後重試，以保持順序；
+// 以 Reject 包裝的失敗移出佇列後繼續送出下一筆。force 為 true 時忽略退避時間。返回成功送出的筆數。
+// 送出期間不持有佇列的鎖，其他呼叫端查詢佇列時不需等待網路請求
+func (o *Outbox) Flush(now time.Time, force bool, send SendFunc) (int, error) {
+	o.flushMu.Lock()
+	defer o.flushMu.Unlock()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.items) == 0 {
+		return 0, nil
+	}
+	if !force && now.Before(o.items[0].NextAttempt) {
+		return 0, nil
+	}
+
+	sent, rejected := 0, false
+	var sendErr error
+	for len(o.items) > 0 && sendErr == nil {
+		item := o.items[0]
+		o.mu.Unlock()
+		err := send(item)
+		o.mu.Lock()
+
+		// 只有 Flush 會移除項目，送出期間 Enqueue 只會附加或更新目標狀態，佇列開頭仍是同一則通知
+		head := &o.items[0]
+		var rej *rejectError
+		switch {
+		case errors.As(err, &rej):
+			head.Attempts++
+			head.LastError = err.Error()
+			o.reject(*head)
+			o.items = o.items[1:]
+			rejected = true
+		case err != nil:
+			head.Attempts++
+			head.LastError = err.Error()
+			head.NextAttempt = now.Add(backoff(head.Attempts))
+			sendErr = err
+		case head.Status != item.Status:
+			// 送出期間目標狀態已改變，保留在開頭重新送出
+			sent++
+		default:
+			sent++
+			o.items = o.items[1:]
+		}
+	}
+
+	if rejected {
+		if err := jsonfile.Save(o.rejectedPath, o.rejected); err != nil {
+			return sent, err
+		}
+	}
+	if err := o.save(); err != nil {
+		return sent, err
+	}
+	return sent, sendErr
+}
+
+// reject 保存無法送出的記錄（呼叫端需持有鎖）
+func (o *Outbox) reject(item Item) {
+	o.rejected = append(o.rejected, item)
+	if len(o.rejected) > maxRejected {
+		o.rejected = o.rejected[len(o.rejected)-maxRejected:]
+	}
+}
+
+// backoff 依失敗次數計算指數退避時間
+func backoff(attempts int) time.Duration {
+	d := minBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+// save 寫入磁碟（呼叫端需持有鎖）
+func (o *Outbox) save() error {
+	if o.items == nil {
+		o.items = []Item{}
+	}
+	return jsonfile.Save(o.path, o.items)
+}
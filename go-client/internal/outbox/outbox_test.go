@@ -0,0 +1,77 @@
+package outbox
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"windows-notification/internal/api"
+)
+
+func TestFlushOrderAndReject(t *testing.T) {
+	o, err := Open(filepath.Join(t.TempDir(), "outbox.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, id := range []string{"1", "2", "3"} {
+		o.Enqueue(api.Notification{ID: id}, api.StatusNotified, now)
+	}
+
+	// 2 被拒絕後移出佇列，3 暫時失敗時停在開頭並延後重試
+	var order []string
+	sent, err := o.Flush(now, false, func(item Item) error {
+		order = append(order, item.Notification.ID)
+		switch item.Notification.ID {
+		case "2":
+			return Reject(errors.New("404"))
+		case "3":
+			return errors.New("timeout")
+		}
+		return nil
+	})
+	if sent != 1 || err == nil || len(order) != 3 {
+		t.Fatalf("Flush() = %d, %v, sent %v", sent, err, order)
+	}
+	if o.Len() != 1 || !o.Contains("3") || len(o.Rejected()) != 1 {
+		t.Fatalf("queue = %d, rejected = %d, want 3 queued and 2 rejected", o.Len(), len(o.Rejected()))
+	}
+	if n, _ := o.Flush(now.Add(time.Second), false, func(Item) error { return nil }); n != 0 {
+		t.Error("Flush() ignored the backoff")
+	}
+
+	// 重新開啟後保留佇列與拒絕記錄
+	o, err = Open(o.path)
+	if err != nil || o.Len() != 1 || len(o.Rejected()) != 1 {
+		t.Fatalf("reopen: %v, queue = %d", err, o.Len())
+	}
+}
+
+func TestFlushDoesNotHoldLockWhileSending(t *testing.T) {
+	o, err := Open(filepath.Join(t.TempDir(), "outbox.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	o.Enqueue(api.Notification{ID: "1"}, api.StatusNotified, now)
+
+	// 送出期間仍可查詢佇列；同一則通知的目標狀態改變時重新送出新的狀態
+	var statuses []int
+	sent, err := o.Flush(now, false, func(item Item) error {
+		statuses = append(statuses, item.Status)
+		if o.Len() != 1 {
+			t.Errorf("Len() = %d during send", o.Len())
+		}
+		if len(statuses) == 1 {
+			o.Enqueue(api.Notification{ID: "1"}, api.StatusUnnotified, now)
+		}
+		return nil
+	})
+	if err != nil || sent != 2 || o.Len() != 0 {
+		t.Fatalf("Flush() = %d, %v, queue = %d", sent, err, o.Len())
+	}
+	if len(statuses) != 2 || statuses[1] != api.StatusUnnotified {
+		t.Errorf("sent statuses = %v, want notified then unnotified", statuses)
+	}
+}
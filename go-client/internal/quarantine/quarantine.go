@@ -0,0 +1,127 @@
+package quarantine
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"windows-notification/internal/api"
+	"windows-notification/internal/jsonfile"
+)
+
+// DefaultThreshold 是隔離前允許的連續顯示失敗次數
+const DefaultThreshold = 3
+
+// Item 代表一則顯示失敗的通知
+type Item struct {
+	Notification  api.Notification `json:"notification"`
+	Failures      int              `json:"failures"`
+	LastError     string           `json:"last_error"`
+	FirstFailedAt time.Time        `json:"first_failed_at"`
+	LastFailedAt  time.Time        `json:"last_failed_at"`
+	Quarantined   bool             `json:"quarantined"`
+}
+
+// Store 記錄每則通知的顯示失敗次數，超過門檻後隔離不再重試
+type Store struct {
+	mu        sync.Mutex
+	path      string
+	threshold int
+	items     map[string]*Item
+}
+
+// Open 開啟（或建立）隔離清單
+func Open(path string, threshold int) (*Store, error) {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	s := &Store{
+		path:      path,
+		threshold: threshold,
+		items:     make(map[string]*Item),
+	}
+	if err := jsonfile.Load(path, &s.items); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return s, nil
+}
+
+// RecordFailure 記錄一次顯示失敗，返回此次是否達到門檻而被隔離
+func (s *Store) RecordFailure(n api.Notification, failure error, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[n.ID]
+	if !ok {
+		item = &Item{FirstFailedAt: now}
+		s.items[n.ID] = item
+	}
+	item.Notification = n
+	item.Failures++
+	item.LastError = failure.Error()
+	item.LastFailedAt = now
+
+	quarantined := !item.Quarantined && item.Failures >= s.threshold
+	if quarantined {
+		item.Quarantined = true
+	}
+	return quarantined, s.save()
+}
+
+// RecordSuccess 清除通知的失敗記錄
+func (s *Store) RecordSuccess(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.items[id]; !ok {
+		return nil
+	}
+	delete(s.items, id)
+	return s.save()
+}
+
+// Contains 判斷通知是否已被隔離
+func (s *Store) Contains(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[id]
+	return ok && item.Quarantined
+}
+
+// List 返回所有已隔離的通知（依最後失敗時間排序）
+func (s *Store) List() []Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []Item
+	for _, item := range s.items {
+		if item.Quarantined {
+			items = append(items, *item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].LastFailedAt.Before(items[j].LastFailedAt)
+	})
+	return items
+}
+
+// Release 將通知移出隔離清單並重設失敗次數
+func (s *Store) Release(id string) (Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[id]
+	if !ok {
+		return Item{}, nil
+	}
+	delete(s.items, id)
+	return *item, s.save()
+}
+
+// save 寫入磁碟（呼叫端需持有鎖）
+func (s *Store) save() error {
+	return jsonfile.Save(s.path, s.items)
+}
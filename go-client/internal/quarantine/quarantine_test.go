@@ -0,0 +1,65 @@
+package quarantine
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"windows-notification/internal/api"
+)
+
+func TestThreshold(t *testing.T) {
+	cases := []struct {
+		threshold int
+		failures  int // 第幾次失敗時隔離
+	}{
+		{0, DefaultThreshold},
+		{1, 1},
+		{5, 5},
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	showErr := errors.New("toast failed")
+	for _, c := range cases {
+		path := filepath.Join(t.TempDir(), "quarantine.json")
+		s, err := Open(path, c.threshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := api.Notification{ID: "1", Title: "Deploy"}
+		for i := 1; i <= c.failures+1; i++ {
+			quarantined, err := s.RecordFailure(n, showErr, now.Add(time.Duration(i)*time.Minute))
+			// 只有達到門檻的那一次返回 true
+			if err != nil || quarantined != (i == c.failures) || s.Contains("1") != (i >= c.failures) {
+				t.Fatalf("threshold %d, failure %d: quarantined = %v, Contains = %v, err = %v", c.threshold, i, quarantined, s.Contains("1"), err)
+			}
+		}
+
+		s, err = Open(path, c.threshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+		items := s.List()
+		if len(items) != 1 || items[0].Failures != c.failures+1 || items[0].LastError != "toast failed" {
+			t.Fatalf("threshold %d: List() after reopen = %+v", c.threshold, items)
+		}
+		if _, err := s.Release("1"); err != nil || s.Contains("1") || len(s.List()) != 0 {
+			t.Errorf("threshold %d: Release() = %v, still quarantined", c.threshold, err)
+		}
+	}
+}
+
+func TestSuccessResetsFailures(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "quarantine.json"), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	n := api.Notification{ID: "1"}
+
+	s.RecordFailure(n, errors.New("x"), now)
+	s.RecordSuccess("1")
+	if quarantined, _ := s.RecordFailure(n, errors.New("x"), now); quarantined || s.Contains("1") {
+		t.Error("failures were not reset by a successful display")
+	}
+}
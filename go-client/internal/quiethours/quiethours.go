@@ -0,0 +1,265 @@
+package quiethours
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"windows-notification/internal/api"
+	"windows-notification/internal/config"
+)
+
+// morningHour 是「暫停到明天」結束的時間（當地時間）
+const morningHour = 8
+
+// defaultBreakthrough 是未設定時可穿透勿擾的優先權
+var defaultBreakthrough = []string{"critical"}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// window 代表解析後的單一勿擾時段
+type window struct {
+	days  map[time.Weekday]bool // 空值代表每天
+	start int                   // 當日分鐘數
+	end   int                   // 當日分鐘數，小於 start 表示跨日
+}
+
+// Controller 管理勿擾時段、手動暫停以及勿擾期間保留的通知
+type Controller struct {
+	mu           sync.Mutex
+	enabled      bool
+	loc          *time.Location
+	windows      []window
+	breakthrough map[string]bool
+	pausedUntil  time.Time
+	held         []api.Notification
+	heldIDs      map[string]bool
+}
+
+// New 依設定建立勿擾控制器
+func New(cfg config.QuietHours) (*Controller, error) {
+	c := &Controller{heldIDs: make(map[string]bool)}
+	if err := c.Update(cfg); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Update 套用新的勿擾設定（手動暫停與保留中的通知不受影響）
+func (c *Controller) Update(cfg config.QuietHours) error {
+	loc := time.Local
+	if cfg.Timezone != "" {
+		l, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return fmt.Errorf("無效的時區 %q: %w", cfg.Timezone, err)
+		}
+		loc = l
+	}
+
+	windows := make([]window, 0, len(cfg.Schedules))
+	for i, s := range cfg.Schedules {
+		w, err := parseWindow(s)
+		if err != nil {
+			return fmt.Errorf("勿擾時段 #%d: %w", i+1, err)
+		}
+		windows = append(windows, w)
+	}
+
+	priorities := cfg.Breakthrough
+	if len(priorities) == 0 {
+		priorities = defaultBreakthrough
+	}
+	breakthrough := make(map[string]bool, len(priorities))
+	for _, p := range priorities {
+		breakthrough[strings.ToLower(strings.TrimSpace(p))] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enabled = cfg.Enabled
+	c.loc = loc
+	c.windows = windows
+	c.breakthrough = breakthrough
+	return nil
+}
+
+// parseWindow 解析單一勿擾時段設定
+func parseWindow(s config.QuietSchedule) (window, error) {
+	start, err := parseClock(s.Start)
+	if err != nil {
+		return window{}, fmt.Errorf("開始時間: %w", err)
+	}
+	end, err := parseClock(s.End)
+	if err != nil {
+		return window{}, fmt.Errorf("結束時間: %w", err)
+	}
+
+	days := make(map[time.Weekday]bool, len(s.Days))
+	for _, d := range s.Days {
+		key := strings.ToLower(strings.TrimSpace(d))
+		if len(key) > 3 {
+			key = key[:3]
+		}
+		wd, ok := weekdays[key]
+		if !ok {
+			return window{}, fmt.Errorf("無效的星期 %q", d)
+		}
+		days[wd] = true
+	}
+
+	return window{days: days, start: start, end: end}, nil
+}
+
+// parseClock 將 HH:MM 轉為當日分鐘數
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("無效的時間 %q（格式應為 HH:MM）", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// includes 判斷時段是否涵蓋指定星期
+func (w window) includes(day time.Weekday) bool {
+	return len(w.days) == 0 || w.days[day]
+}
+
+// contains 判斷時間點是否落在時段內
+func (w window) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+
+	switch {
+	case w.start == w.end:
+		return w.includes(day)
+	case w.start < w.end:
+		return w.includes(day) && minute >= w.start && minute < w.end
+	default:
+		// 跨日時段：前半段屬於當天，後半段屬於前一天
+		prev := (day + 6) % 7
+		return (w.includes(day) && minute >= w.start) || (w.includes(prev) && minute < w.end)
+	}
+}
+
+// Active 判斷目前是否處於勿擾狀態（手動暫停或排程時段）
+func (c *Controller) Active(now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Before(c.pausedUntil) {
+		return true
+	}
+	if !c.enabled {
+		return false
+	}
+
+	local := now.In(c.loc)
+	for _, w := range c.windows {
+		if w.contains(local) {
+			return true
+		}
+	}
+	return false
+}
+
+// PauseFor 手動暫停通知一段時間
+func (c *Controller) PauseFor(now time.Time, d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pausedUntil = now.Add(d)
+	return c.pausedUntil
+}
+
+// PauseUntilTomorrow 手動暫停通知到明天早上
+func (c *Controller) PauseUntilTomorrow(now time.Time) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pausedUntil = TomorrowMorning(now.In(c.loc))
+	return c.pausedUntil
+}
+
+// Resume 取消手動暫停
+func (c *Controller) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pausedUntil = time.Time{}
+}
+
+// PausedUntil 返回手動暫停的結束時間（未暫停時為零值）
+func (c *Controller) PausedUntil(now time.Time) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.Before(c.pausedUntil) {
+		return c.pausedUntil
+	}
+	return time.Time{}
+}
+
+// Breaks 判斷通知的優先權是否可穿透勿擾
+func (c *Controller) Breaks(n api.Notification) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.breakthrough[strings.ToLower(n.Priority)]
+}
+
+// Hold 保留勿擾期間收到的通知，返回是否為新保留的項目
+func (c *Controller) Hold(n api.Notification) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.heldIDs[n.ID] {
+		return false
+	}
+	c.heldIDs[n.ID] = true
+	c.held = append(c.held, n)
+	return true
+}
+
+// HeldCount 返回目前保留中的通知數量
+func (c *Controller) HeldCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.held)
+}
+
+// Release 取出所有保留中的通知（依收到順序）
+func (c *Controller) Release() []api.Notification {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	held := c.held
+	c.held = nil
+	c.heldIDs = make(map[string]bool)
+	return held
+}
+
+// Digest 將保留的通知整理為摘要通知的標題與內容
+func Digest(held []api.Notification, maxLines int) (string, string) {
+	title := fmt.Sprintf("勿擾期間收到 %d 則通知", len(held))
+
+	var b strings.Builder
+	for i, n := range held {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if i == maxLines {
+			fmt.Fprintf(&b, "…以及另外 %d 則", len(held)-maxLines)
+			break
+		}
+		fmt.Fprintf(&b, "• [%s] %s", n.Project, n.Title)
+	}
+	return title, b.String()
+}
+
+// TomorrowMorning 返回隔天早上的時間點（依 t 的時區）
+func TomorrowMorning(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d+1, morningHour, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,109 @@
+package quiethours
+
+import (
+	"testing"
+	"time"
+
+	"windows-notification/internal/api"
+	"windows-notification/internal/config"
+)
+
+func TestActiveAcrossMidnight(t *testing.T) {
+	q, err := New(config.QuietHours{
+		Enabled:   true,
+		Timezone:  "Asia/Taipei",
+		Schedules: []config.QuietSchedule{{Days: []string{"Mon"}, Start: "22:00", End: "07:00"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	taipei, _ := time.LoadLocation("Asia/Taipei")
+	at := func(day, hour, minute int) time.Time {
+		// 2024-05-06 是星期一
+		return time.Date(2024, 5, day, hour, minute, 0, 0, taipei)
+	}
+
+	cases := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{"monday before start", at(6, 21, 59), false},
+		{"monday at start", at(6, 22, 0), true},
+		{"monday before midnight", at(6, 23, 59), true},
+		// 跨過午夜的部分屬於開始的那天（星期一）的時段
+		{"tuesday after midnight", at(7, 0, 0), true},
+		{"tuesday before end", at(7, 6, 59), true},
+		{"tuesday at end", at(7, 7, 0), false},
+		{"tuesday night", at(7, 23, 0), false},
+		{"monday early morning belongs to sunday", at(6, 3, 0), false},
+		// 以設定的時區判斷，不受呼叫端時區影響
+		{"utc input", time.Date(2024, 5, 6, 15, 30, 0, 0, time.UTC), true},
+	}
+	for _, c := range cases {
+		if got := q.Active(c.t); got != c.want {
+			t.Errorf("%s: Active(%s) = %v, want %v", c.name, c.t.Format("Mon 15:04"), got, c.want)
+		}
+	}
+
+	// 停用時只有手動暫停有效
+	q.Update(config.QuietHours{Schedules: []config.QuietSchedule{{Start: "00:00", End: "00:00"}}})
+	if q.Active(at(6, 23, 0)) {
+		t.Error("disabled schedule is active")
+	}
+	until := q.PauseFor(at(6, 12, 0), 30*time.Minute)
+	if !q.Active(at(6, 12, 29)) || q.Active(until) {
+		t.Error("manual pause should cover exactly 30 minutes")
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  config.QuietHours
+	}{
+		{"timezone", config.QuietHours{Timezone: "Mars/Olympus"}},
+		{"start", config.QuietHours{Schedules: []config.QuietSchedule{{Start: "25:00", End: "07:00"}}}},
+		{"end", config.QuietHours{Schedules: []config.QuietSchedule{{Start: "22:00", End: "7am"}}}},
+		{"day", config.QuietHours{Schedules: []config.QuietSchedule{{Days: []string{"xyz"}, Start: "22:00", End: "07:00"}}}},
+	}
+	for _, c := range cases {
+		if _, err := New(c.cfg); err == nil {
+			t.Errorf("%s: New() succeeded, want error", c.name)
+		}
+	}
+
+	// 更新失敗時保留原本的設定
+	c, _ := New(config.QuietHours{Enabled: true, Schedules: []config.QuietSchedule{{Start: "00:00", End: "00:00"}}})
+	if err := c.Update(config.QuietHours{Enabled: true, Timezone: "Nowhere/Zone"}); err == nil {
+		t.Fatal("Update() with invalid timezone succeeded")
+	}
+	if !c.Active(time.Now()) {
+		t.Error("failed Update() changed the schedule")
+	}
+}
+
+func TestBreakthrough(t *testing.T) {
+	cases := []struct {
+		name       string
+		priorities []string
+		priority   string
+		want       bool
+	}{
+		{"default critical", nil, "critical", true},
+		{"default is case insensitive", nil, "CRITICAL", true},
+		{"default high", nil, "high", false},
+		{"configured", []string{"High ", "urgent"}, "high", true},
+		{"configured replaces default", []string{"urgent"}, "critical", false},
+		{"empty priority", []string{"urgent"}, "", false},
+	}
+	for _, c := range cases {
+		q, err := New(config.QuietHours{Breakthrough: c.priorities})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := q.Breaks(api.Notification{Priority: c.priority}); got != c.want {
+			t.Errorf("%s: Breaks(%q) = %v, want %v", c.name, c.priority, got, c.want)
+		}
+	}
+}
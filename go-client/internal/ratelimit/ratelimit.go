@@ -0,0 +1,211 @@
+package ratelimit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"windows-notification/internal/api"
+	"windows-notification/internal/config"
+)
+
+// bucket 是每分鐘補充固定數量的令牌桶
+type bucket struct {
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+// newBucket 建立已補滿的令牌桶
+func newBucket(perMinute int, now time.Time) *bucket {
+	return &bucket{capacity: float64(perMinute), tokens: float64(perMinute), last: now}
+}
+
+// refill 依經過時間補充令牌
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens += elapsed * b.capacity / 60
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+	}
+	b.last = now
+}
+
+// available 判斷是否有可用令牌
+func (b *bucket) available(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= 1
+}
+
+// Item 代表等待顯示的通知
+type Item struct {
+	Notification api.Notification
+	Notifier     string // 顯示用的通知後端
+}
+
+// Limiter 以令牌桶限制通知顯示速度，超出的通知依序排隊等待
+type Limiter struct {
+	mu         sync.Mutex
+	global     int
+	perProject int
+	threshold  int
+	globalB    *bucket
+	projects   map[string]*bucket
+	queue      []Item
+	queued     map[string]bool
+}
+
+// New 依設定建立限流器；令牌桶在第一次使用時以呼叫端傳入的時間建立
+func New(cfg config.RateLimit) *Limiter {
+	return &Limiter{
+		global:     cfg.Global,
+		perProject: cfg.PerProject,
+		threshold:  cfg.SummaryThreshold,
+		projects:   make(map[string]*bucket),
+		queued:     make(map[string]bool),
+	}
+}
+
+// globalBucket 取得全部專案共用的令牌桶（呼叫端需持有鎖）
+func (l *Limiter) globalBucket(now time.Time) *bucket {
+	if l.global <= 0 {
+		return nil
+	}
+	if l.globalB == nil {
+		l.globalB = newBucket(l.global, now)
+	}
+	return l.globalB
+}
+
+// projectBucket 取得專案的令牌桶（呼叫端需持有鎖）
+func (l *Limiter) projectBucket(project string, now time.Time) *bucket {
+	if l.perProject <= 0 {
+		return nil
+	}
+	b, ok := l.projects[project]
+	if !ok {
+		b = newBucket(l.perProject, now)
+		l.projects[project] = b
+	}
+	return b
+}
+
+// take 嘗試為專案取得一個令牌（呼叫端需持有鎖）
+func (l *Limiter) take(project string, now time.Time) bool {
+	gb, pb := l.globalBucket(now), l.projectBucket(project, now)
+	if gb != nil && !gb.available(now) {
+		return false
+	}
+	if pb != nil && !pb.available(now) {
+		return false
+	}
+	if gb != nil {
+		gb.tokens--
+	}
+	if pb != nil {
+		pb.tokens--
+	}
+	return true
+}
+
+// Allow 判斷專案目前是否可以顯示一則通知；佇列中仍有同專案的通知時不插隊
+func (l *Limiter) Allow(project string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, item := range l.queue {
+		if l.global > 0 || item.Notification.Project == project {
+			return false
+		}
+	}
+	return l.take(project, now)
+}
+
+// Enqueue 將通知加入等待佇列，已在佇列中的通知會被忽略
+func (l *Limiter) Enqueue(item Item) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.queued[item.Notification.ID] {
+		return false
+	}
+	l.queued[item.Notification.ID] = true
+	l.queue = append(l.queue, item)
+	return true
+}
+
+// Queued 判斷通知是否已在等待佇列中
+func (l *Limiter) Queued(id string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.queued[id]
+}
+
+// Len 返回等待佇列長度
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.queue)
+}
+
+// Release 依序取出目前令牌足夠顯示的通知
+func (l *Limiter) Release(now time.Time) []Item {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var released []Item
+	remaining := l.queue[:0]
+	for _, item := range l.queue {
+		if l.take(item.Notification.Project, now) {
+			released = append(released, item)
+			delete(l.queued, item.Notification.ID)
+			continue
+		}
+		remaining = append(remaining, item)
+	}
+	l.queue = remaining
+	return released
+}
+
+// Overflow 當佇列長度達到摘要門檻時取出全部通知，否則返回 nil
+func (l *Limiter) Overflow() []Item {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.threshold <= 0 || len(l.queue) < l.threshold {
+		return nil
+	}
+	items := l.queue
+	l.queue = nil
+	l.queued = make(map[string]bool)
+	return items
+}
+
+// Summary 將多則通知整理為摘要通知的標題與內容
+func Summary(items []Item) (string, string) {
+	counts := make(map[string]int)
+	for _, item := range items {
+		counts[item.Notification.Project]++
+	}
+
+	projects := make([]string, 0, len(counts))
+	for p := range counts {
+		projects = append(projects, p)
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		if counts[projects[i]] != counts[projects[j]] {
+			return counts[projects[i]] > counts[projects[j]]
+		}
+		return projects[i] < projects[j]
+	})
+
+	lines := make([]string, len(projects))
+	for i, p := range projects {
+		lines[i] = fmt.Sprintf("• %s: %d 則", p, counts[p])
+	}
+	return fmt.Sprintf("通知過多，已合併 %d 則", len(items)), strings.Join(lines, "\n")
+}
@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"windows-notification/internal/api"
+	"windows-notification/internal/config"
+)
+
+// clock 是測試用的時鐘，只在呼叫 advance 時前進
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newClock() *clock {
+	return &clock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func item(id, project string) Item {
+	return Item{Notification: api.Notification{ID: id, Project: project}}
+}
+
+func TestBucketRefill(t *testing.T) {
+	c := newClock()
+	l := New(config.RateLimit{Enabled: true, PerProject: 2})
+
+	steps := []struct {
+		advance time.Duration
+		want    []bool // 連續呼叫 Allow 的結果
+	}{
+		{0, []bool{true, true, false}},
+		{29 * time.Second, []bool{false}},             // 每分鐘 2 個，30 秒補充 1 個
+		{time.Second, []bool{true, false}},            // 剛好補充 1 個
+		{10 * time.Minute, []bool{true, true, false}}, // 最多補滿容量
+	}
+	for i, s := range steps {
+		c.advance(s.advance)
+		for j, want := range s.want {
+			if got := l.Allow("web", c.now()); got != want {
+				t.Errorf("step %d call %d: Allow() = %v, want %v", i, j, got, want)
+			}
+		}
+	}
+}
+
+func TestProjectAndGlobalLimits(t *testing.T) {
+	cases := []struct {
+		name     string
+		cfg      config.RateLimit
+		projects []string
+		want     []bool
+	}{
+		{"per project", config.RateLimit{PerProject: 1}, []string{"a", "b", "a", "b", "c"}, []bool{true, true, false, false, true}},
+		{"global", config.RateLimit{Global: 2}, []string{"a", "b", "c"}, []bool{true, true, false}},
+		{"both", config.RateLimit{Global: 3, PerProject: 2}, []string{"a", "a", "a", "b", "b"}, []bool{true, true, false, true, false}},
+		{"unlimited", config.RateLimit{}, []string{"a", "a", "a", "a"}, []bool{true, true, true, true}},
+	}
+	for _, tc := range cases {
+		c := newClock()
+		l := New(tc.cfg)
+		for i, p := range tc.projects {
+			if got := l.Allow(p, c.now()); got != tc.want[i] {
+				t.Errorf("%s: Allow(%q) #%d = %v, want %v", tc.name, p, i, got, tc.want[i])
+			}
+		}
+	}
+}
+
+func TestQueueOrder(t *testing.T) {
+	c := newClock()
+	l := New(config.RateLimit{PerProject: 1})
+	l.Allow("a", c.now())
+
+	l.Enqueue(item("1", "a"))
+	l.Enqueue(item("2", "a"))
+	if l.Enqueue(item("1", "a")) {
+		t.Error("Enqueue() accepted a queued notification twice")
+	}
+
+	// 佇列中仍有同專案的通知時不插隊，其他專案不受影響
+	c.advance(time.Minute)
+	if l.Allow("a", c.now()) {
+		t.Error("Allow(a) jumped the queue")
+	}
+	if !l.Allow("b", c.now()) {
+		t.Error("Allow(b) blocked by another project's queue")
+	}
+
+	released := l.Release(c.now())
+	if len(released) != 1 || released[0].Notification.ID != "1" || !l.Queued("2") {
+		t.Fatalf("Release() = %+v, want only 1", released)
+	}
+	c.advance(time.Minute)
+	if released := l.Release(c.now()); len(released) != 1 || released[0].Notification.ID != "2" || l.Len() != 0 {
+		t.Fatalf("Release() = %+v, want 2", released)
+	}
+}
+
+func TestSummaryThreshold(t *testing.T) {
+	l := New(config.RateLimit{PerProject: 1, SummaryThreshold: 3})
+	l.Enqueue(item("1", "web"))
+	l.Enqueue(item("2", "api"))
+	if items := l.Overflow(); items != nil {
+		t.Fatalf("Overflow() = %+v below threshold", items)
+	}
+
+	l.Enqueue(item("3", "web"))
+	items := l.Overflow()
+	if len(items) != 3 || l.Len() != 0 || l.Queued("1") {
+		t.Fatalf("Overflow() = %d items, queue %d, want all 3 taken", len(items), l.Len())
+	}
+
+	title, message := Summary(items)
+	if title != "通知過多，已合併 3 則" || message != "• web: 2 則\n• api: 1 則" {
+		t.Errorf("Summary() = %q, %q", title, message)
+	}
+
+	// 未設定門檻時不合併
+	l = New(config.RateLimit{PerProject: 1})
+	for _, id := range []string{"1", "2", "3", "4"} {
+		l.Enqueue(item(id, "web"))
+	}
+	if l.Overflow() != nil {
+		t.Error("Overflow() without threshold")
+	}
+}
@@ -0,0 +1,195 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"windows-notification/internal/api"
+)
+
+// DefaultKey 是套用到所有未個別設定專案的樣板鍵
+const DefaultKey = "*"
+
+// timeLayouts 是 created_at 可能的時間格式
+var timeLayouts = []string{"2006-01-02 15:04:05", time.RFC3339}
+
+// Spec 代表一組標題與內容樣板，留空的欄位保留原始內容
+type Spec struct {
+	Title   string `json:"title"`
+	Message string `json:"message"`
+}
+
+// Funcs 返回樣板可使用的函式
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		"upper":    strings.ToUpper,
+		"lower":    strings.ToLower,
+		"trim":     strings.TrimSpace,
+		"replace":  func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"truncate": truncate,
+		"default":  defaultValue,
+		"date":     date,
+		"ago":      ago,
+	}
+}
+
+// truncate 將字串截斷為最多 n 個字元，超過時以 … 結尾
+func truncate(n int, s string) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-1]) + "…"
+}
+
+// defaultValue 在字串為空時返回預設值
+func defaultValue(def, s string) string {
+	if strings.TrimSpace(s) == "" {
+		return def
+	}
+	return s
+}
+
+// parseTime 解析 API 回傳的時間字串（當地時間）
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("無法解析時間 %q", s)
+}
+
+// date 以 Go 時間格式重新格式化時間字串，無法解析時返回原字串
+func date(layout, s string) string {
+	t, err := parseTime(s)
+	if err != nil {
+		return s
+	}
+	return t.Format(layout)
+}
+
+// ago 將時間字串轉為相對時間，例如「5 分鐘前」
+func ago(s string) string {
+	t, err := parseTime(s)
+	if err != nil {
+		return s
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "剛剛"
+	case d < time.Hour:
+		return fmt.Sprintf("%d 分鐘前", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%d 小時前", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%d 天前", int(d.Hours()/24))
+	}
+}
+
+// Template 代表已編譯的標題與內容樣板
+type Template struct {
+	title   *template.Template
+	message *template.Template
+}
+
+// Compile 編譯一組樣板
+func Compile(spec Spec) (*Template, error) {
+	t := &Template{}
+	var err error
+	if t.title, err = parse("title", spec.Title); err != nil {
+		return nil, fmt.Errorf("標題樣板: %w", err)
+	}
+	if t.message, err = parse("message", spec.Message); err != nil {
+		return nil, fmt.Errorf("內容樣板: %w", err)
+	}
+	return t, nil
+}
+
+// parse 編譯單一樣板，空字串返回 nil
+func parse(name, src string) (*template.Template, error) {
+	if src == "" {
+		return nil, nil
+	}
+	return template.New(name).Funcs(Funcs()).Option("missingkey=zero").Parse(src)
+}
+
+// execute 套用單一樣板，樣板為 nil 時返回原始值
+func execute(t *template.Template, n api.Notification, raw string) (string, error) {
+	if t == nil {
+		return raw, nil
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, n); err != nil {
+		return raw, err
+	}
+	return b.String(), nil
+}
+
+// Render 以通知內容套用樣板
+func (t *Template) Render(n api.Notification) (string, string, error) {
+	title, err := execute(t.title, n, n.Title)
+	if err != nil {
+		return n.Title, n.Message, fmt.Errorf("標題樣板: %w", err)
+	}
+	message, err := execute(t.message, n, n.Message)
+	if err != nil {
+		return n.Title, n.Message, fmt.Errorf("內容樣板: %w", err)
+	}
+	return title, message, nil
+}
+
+// Set 依專案管理樣板
+type Set struct {
+	templates map[string]*Template
+}
+
+// NewSet 編譯所有專案的樣板，任何錯誤都會指出是哪個專案
+func NewSet(specs map[string]Spec) (*Set, error) {
+	s := &Set{templates: make(map[string]*Template, len(specs))}
+	for project, spec := range specs {
+		t, err := Compile(spec)
+		if err != nil {
+			return nil, fmt.Errorf("專案 %q 的%w", project, err)
+		}
+		s.templates[project] = t
+	}
+	return s, nil
+}
+
+// Render 以專案對應的樣板（沒有則使用 *）格式化通知；沒有樣板時返回原始內容
+func (s *Set) Render(n api.Notification) (string, string, error) {
+	t, ok := s.templates[n.Project]
+	if !ok {
+		t, ok = s.templates[DefaultKey]
+	}
+	if !ok {
+		return n.Title, n.Message, nil
+	}
+	return t.Render(n)
+}
+
+// Sample 返回預覽用的範例通知
+func Sample(project string) api.Notification {
+	if project == "" {
+		project = "demo"
+	}
+	return api.Notification{
+		ID:        "0",
+		Project:   project,
+		Title:     "Deploy failed",
+		Message:   "Pipeline #128 failed at stage \"test\": 3 of 214 tests failed. See the job log for details.",
+		Type:      "ci",
+		Priority:  "high",
+		Repo:      "example/backend",
+		Branch:    "main",
+		ActionURL: "https://ci.example.com/pipelines/128",
+		Metadata:  map[string]interface{}{"pipeline": 128},
+		Status:    "0",
+		CreatedAt: time.Now().Add(-3 * time.Minute).Format(timeLayouts[0]),
+	}
+}
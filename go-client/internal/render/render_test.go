@@ -0,0 +1,78 @@
+package render
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"windows-notification/internal/api"
+)
+
+func TestFuncs(t *testing.T) {
+	n := Sample("web")
+	n.CreatedAt = "2024-05-01 09:30:00"
+	cases := []struct {
+		tmpl string
+		want string
+	}{
+		{`{{upper .Project}}`, "WEB"},
+		{`{{.Title | lower}}`, "deploy failed"},
+		{`{{trim "  x  "}}`, "x"},
+		{`{{replace "failed" "ok" .Title}}`, "Deploy ok"},
+		{`{{truncate 10 .Message}}`, "Pipeline …"},
+		{`{{truncate 5 "短字串"}}`, "短字串"},
+		{`{{default "none" .Type}} {{default "none" ""}}`, "ci none"},
+		{`{{date "01/02 15:04" .CreatedAt}}`, "05/01 09:30"},
+		{`{{date "01/02" "yesterday"}}`, "yesterday"},
+		{`{{index .Metadata "pipeline"}}`, "128"},
+	}
+	for _, c := range cases {
+		tmpl, err := Compile(Spec{Title: c.tmpl})
+		if err != nil {
+			t.Fatalf("Compile(%q) = %v", c.tmpl, err)
+		}
+		title, message, err := tmpl.Render(n)
+		if err != nil || title != c.want || message != n.Message {
+			t.Errorf("Render(%q) = %q, %v, want %q", c.tmpl, title, err, c.want)
+		}
+	}
+
+	n.CreatedAt = time.Now().Add(-90 * time.Minute).Format(timeLayouts[0])
+	if got := ago(n.CreatedAt); got != "1 小時前" {
+		t.Errorf("ago() = %q", got)
+	}
+}
+
+func TestSetFallback(t *testing.T) {
+	if _, err := NewSet(map[string]Spec{"web": {Title: "{{.Title"}}); err == nil || !strings.Contains(err.Error(), `"web"`) {
+		t.Errorf("NewSet() = %v, want parse error naming the project", err)
+	}
+
+	s, err := NewSet(map[string]Spec{
+		DefaultKey: {Title: "[{{.Project}}] {{.Title}}"},
+		"api":      {Message: "{{.Missing}}"}, // 執行時才失敗
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		project        string
+		title, message string
+		fails          bool
+	}{
+		{"web", "[web] Deploy failed", "body", false},
+		{"api", "Deploy failed", "body", true},
+	}
+	for _, c := range cases {
+		n := api.Notification{Project: c.project, Title: "Deploy failed", Message: "body"}
+		title, message, err := s.Render(n)
+		if (err != nil) != c.fails || title != c.title || message != c.message {
+			t.Errorf("%s: Render() = %q, %q, %v", c.project, title, message, err)
+		}
+	}
+
+	empty, _ := NewSet(nil)
+	if title, message, err := empty.Render(api.Notification{Title: "a", Message: "b"}); title != "a" || message != "b" || err != nil {
+		t.Errorf("Render() without templates = %q, %q, %v", title, message, err)
+	}
+}
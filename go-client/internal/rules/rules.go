@@ -0,0 +1,214 @@
+package rules
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"windows-notification/internal/api"
+	"windows-notification/internal/config"
+)
+
+// 規則動作類型
+const (
+	ActionShow         = "show"
+	ActionSuppress     = "suppress"
+	ActionAck          = "ack"
+	ActionPriority     = "priority"
+	ActionRewriteTitle = "rewrite_title"
+	ActionRoute        = "route"
+	ActionForward      = "forward"
+)
+
+// Decision 代表一則通知經過規則後的結果
+type Decision struct {
+	Notification api.Notification // 經過轉換後的通知
+	Action       string           // 最終動作：show、suppress、ack 或 forward
+	Notifier     string           // 顯示用的通知後端，空字串代表預設後端
+	ForwardURL   string           // forward 動作的轉送網址
+	Matched      []string         // 依序命中的規則名稱
+}
+
+// compiledRule 代表已編譯的規則
+type compiledRule struct {
+	name     string
+	match    config.RuleMatch
+	title    *regexp.Regexp
+	message  *regexp.Regexp
+	metadata map[string]*regexp.Regexp
+	action   config.RuleAction
+}
+
+// Engine 依序套用通知規則
+type Engine struct {
+	rules []compiledRule
+}
+
+// New 編譯規則設定，任何無效的規則都會返回錯誤
+func New(cfg []config.Rule) (*Engine, error) {
+	e := &Engine{rules: make([]compiledRule, 0, len(cfg))}
+
+	for i, r := range cfg {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("rule #%d", i+1)
+		}
+
+		cr, err := compile(name, r)
+		if err != nil {
+			return nil, fmt.Errorf("規則 %q: %w", name, err)
+		}
+		e.rules = append(e.rules, cr)
+	}
+
+	return e, nil
+}
+
+// compile 編譯單一規則
+func compile(name string, r config.Rule) (compiledRule, error) {
+	cr := compiledRule{name: name, match: r.Match, action: r.Action}
+
+	for _, pattern := range []string{r.Match.Project, r.Match.Priority, r.Match.Type, r.Match.Repo, r.Match.Branch} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return cr, fmt.Errorf("無效的萬用字元 %q: %w", pattern, err)
+		}
+	}
+
+	var err error
+	if cr.title, err = compileRegexp(r.Match.Title); err != nil {
+		return cr, fmt.Errorf("標題: %w", err)
+	}
+	if cr.message, err = compileRegexp(r.Match.Message); err != nil {
+		return cr, fmt.Errorf("內容: %w", err)
+	}
+
+	cr.metadata = make(map[string]*regexp.Regexp, len(r.Match.Metadata))
+	for key, pattern := range r.Match.Metadata {
+		if cr.metadata[key], err = compileRegexp(pattern); err != nil {
+			return cr, fmt.Errorf("metadata %q: %w", key, err)
+		}
+	}
+
+	switch r.Action.Type {
+	case ActionShow, ActionSuppress, ActionAck:
+	case ActionPriority:
+		if r.Action.Priority == "" {
+			return cr, fmt.Errorf("priority 動作需要 priority")
+		}
+	case ActionRewriteTitle:
+		if r.Action.Title == "" {
+			return cr, fmt.Errorf("rewrite_title 動作需要 title")
+		}
+	case ActionRoute:
+		if r.Action.Notifier == "" {
+			return cr, fmt.Errorf("route 動作需要 notifier")
+		}
+	case ActionForward:
+		if r.Action.URL == "" {
+			return cr, fmt.Errorf("forward 動作需要 url")
+		}
+	default:
+		return cr, fmt.Errorf("未知的動作 %q", r.Action.Type)
+	}
+
+	return cr, nil
+}
+
+// compileRegexp 編譯正規表示式，空字串返回 nil
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
+
+// matches 判斷通知是否符合規則的全部條件
+func (r *compiledRule) matches(n api.Notification) bool {
+	globs := []struct{ pattern, value string }{
+		{r.match.Project, n.Project},
+		{r.match.Priority, n.Priority},
+		{r.match.Type, n.Type},
+		{r.match.Repo, n.Repo},
+		{r.match.Branch, n.Branch},
+	}
+	for _, g := range globs {
+		if g.pattern == "" {
+			continue
+		}
+		if ok, _ := path.Match(g.pattern, g.value); !ok {
+			return false
+		}
+	}
+
+	if r.title != nil && !r.title.MatchString(n.Title) {
+		return false
+	}
+	if r.message != nil && !r.message.MatchString(n.Message) {
+		return false
+	}
+
+	for key, re := range r.metadata {
+		value, ok := n.Metadata[key]
+		if !ok {
+			return false
+		}
+		if re != nil && !re.MatchString(fmt.Sprint(value)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Evaluate 依序套用規則；priority 與 rewrite_title 會繼續比對後續規則，
+// 其餘動作命中即停止。沒有任何終止動作命中時以預設後端顯示。
+func (e *Engine) Evaluate(n api.Notification) Decision {
+	d := Decision{Notification: n, Action: ActionShow}
+
+	for i := range e.rules {
+		r := &e.rules[i]
+		if !r.matches(d.Notification) {
+			continue
+		}
+		d.Matched = append(d.Matched, r.name)
+
+		switch r.action.Type {
+		case ActionPriority:
+			d.Notification.Priority = r.action.Priority
+			continue
+		case ActionRewriteTitle:
+			d.Notification.Title = strings.ReplaceAll(r.action.Title, "{title}", d.Notification.Title)
+			continue
+		case ActionRoute:
+			d.Action = ActionShow
+			d.Notifier = r.action.Notifier
+		case ActionForward:
+			d.Action = ActionForward
+			d.ForwardURL = r.action.URL
+		default:
+			d.Action = r.action.Type
+		}
+		return d
+	}
+
+	return d
+}
+
+// Describe 返回決策的簡短說明，供 dry run 顯示
+func (d Decision) Describe() string {
+	matched := "(no rule)"
+	if len(d.Matched) > 0 {
+		matched = strings.Join(d.Matched, " → ")
+	}
+
+	action := d.Action
+	switch {
+	case d.Action == ActionShow && d.Notifier != "":
+		action += " via " + d.Notifier
+	case d.Action == ActionForward:
+		action += " to " + d.ForwardURL
+	}
+
+	return fmt.Sprintf("%s ⇒ %s", matched, action)
+}
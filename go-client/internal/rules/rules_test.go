@@ -0,0 +1,70 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+
+	"windows-notification/internal/api"
+	"windows-notification/internal/config"
+)
+
+func TestEvaluateOrder(t *testing.T) {
+	e, err := New([]config.Rule{
+		{Name: "urgent prod", Match: config.RuleMatch{Branch: "main", Title: "(?i)failed"}, Action: config.RuleAction{Type: ActionPriority, Priority: "critical"}},
+		{Name: "tag", Match: config.RuleMatch{Project: "web*"}, Action: config.RuleAction{Type: ActionRewriteTitle, Title: "[web] {title}"}},
+		{Name: "page", Match: config.RuleMatch{Priority: "critical"}, Action: config.RuleAction{Type: ActionRoute, Notifier: "pager"}},
+		{Name: "bots", Match: config.RuleMatch{Metadata: map[string]string{"bot": ""}}, Action: config.RuleAction{Type: ActionSuppress}},
+		{Name: "audit", Match: config.RuleMatch{Type: "audit"}, Action: config.RuleAction{Type: ActionForward, URL: "https://hooks.example.com"}},
+		{Name: "never", Match: config.RuleMatch{Project: "web*"}, Action: config.RuleAction{Type: ActionAck}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		notif    api.Notification
+		action   string
+		notifier string
+		title    string
+		matched  []string
+	}{
+		// 轉換動作繼續比對，後面的規則看到的是轉換後的通知
+		{"transform then route", api.Notification{Project: "web-app", Branch: "main", Title: "Build failed"},
+			ActionShow, "pager", "[web] Build failed", []string{"urgent prod", "tag", "page"}},
+		// 第一個終止動作命中後不再比對
+		{"stop at first terminal", api.Notification{Project: "api", Metadata: map[string]interface{}{"bot": true}, Type: "audit"},
+			ActionSuppress, "", "", []string{"bots"}},
+		{"forward", api.Notification{Project: "api", Type: "audit", Title: "Login"},
+			ActionForward, "", "Login", []string{"audit"}},
+		{"terminal after transform", api.Notification{Project: "web-app", Title: "ok"},
+			ActionAck, "", "[web] ok", []string{"tag", "never"}},
+		{"no rule", api.Notification{Project: "api", Title: "ok"},
+			ActionShow, "", "ok", nil},
+	}
+	for _, c := range cases {
+		d := e.Evaluate(c.notif)
+		if d.Action != c.action || d.Notifier != c.notifier || d.Notification.Title != c.title || !reflect.DeepEqual(d.Matched, c.matched) {
+			t.Errorf("%s: Evaluate() = %s %q %q %v, want %s %q %q %v", c.name,
+				d.Action, d.Notifier, d.Notification.Title, d.Matched, c.action, c.notifier, c.title, c.matched)
+		}
+	}
+	if d := e.Evaluate(cases[2].notif); d.ForwardURL != "https://hooks.example.com" || d.Describe() != "audit ⇒ forward to https://hooks.example.com" {
+		t.Errorf("forward decision = %+v, %q", d, d.Describe())
+	}
+}
+
+func TestNewRejectsInvalid(t *testing.T) {
+	cases := []config.Rule{
+		{Action: config.RuleAction{Type: "drop"}},
+		{Action: config.RuleAction{Type: ActionPriority}},
+		{Match: config.RuleMatch{Title: "("}, Action: config.RuleAction{Type: ActionShow}},
+		{Match: config.RuleMatch{Project: "["}, Action: config.RuleAction{Type: ActionShow}},
+		{Match: config.RuleMatch{Metadata: map[string]string{"k": "*"}}, Action: config.RuleAction{Type: ActionShow}},
+	}
+	for i, r := range cases {
+		if _, err := New([]config.Rule{r}); err == nil {
+			t.Errorf("case %d: New() accepted %+v", i, r)
+		}
+	}
+}
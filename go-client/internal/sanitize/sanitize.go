@@ -0,0 +1,144 @@
+package sanitize
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Ellipsis 是截斷時附加的符號
+const Ellipsis = "…"
+
+// Limits 代表後端可顯示的最大字元數，0 代表不限制
+type Limits struct {
+	Title   int
+	Message int
+}
+
+var (
+	htmlTag      = regexp.MustCompile(`(?s)<[a-zA-Z/!][^>]*>`)
+	htmlBreak    = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|tr|h[1-6])\s*>`)
+	htmlListItem = regexp.MustCompile(`(?i)<li[^>]*>`)
+	mdFence      = regexp.MustCompile("(?m)^\\s*(```|~~~).*$")
+	mdImage      = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink       = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+	mdBold       = regexp.MustCompile(`(\*\*|__)([^*_\n]+)(\*\*|__)`)
+	mdItalic     = regexp.MustCompile(`\*([^*\s][^*\n]*?)\*`)
+	mdCode       = regexp.MustCompile("`([^`\n]+)`")
+	mdHeading    = regexp.MustCompile(`(?m)^\s{0,3}#{1,6}\s+`)
+	mdQuote      = regexp.MustCompile(`(?m)^\s{0,3}>\s?`)
+	mdBullet     = regexp.MustCompile(`(?m)^(\s*)[-*+]\s+`)
+	blankLines   = regexp.MustCompile(`\n{3,}`)
+)
+
+// StripControl 移除控制字元、雙向文字覆寫字元與無效的 UTF-8，保留換行與 Tab
+func StripControl(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		switch {
+		case r == '\n' || r == '\t':
+			b.WriteRune(r)
+		case r == '\r':
+			b.WriteRune('\n')
+		case r == utf8.RuneError, unicode.IsControl(r), isBidiControl(r):
+			// 丟棄
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isBidiControl 判斷是否為可能用來偽裝內容的雙向文字控制字元
+func isBidiControl(r rune) bool {
+	return (r >= '\u202a' && r <= '\u202e') || (r >= '\u2066' && r <= '\u2069') || r == '\u200e' || r == '\u200f'
+}
+
+// PlainText 將 HTML 或 Markdown 轉為純文字
+func PlainText(s string) string {
+	if htmlTag.MatchString(s) {
+		s = htmlBreak.ReplaceAllString(s, "\n")
+		s = htmlListItem.ReplaceAllString(s, "• ")
+		s = htmlTag.ReplaceAllString(s, "")
+	}
+	s = html.UnescapeString(s)
+
+	s = mdFence.ReplaceAllString(s, "")
+	s = mdImage.ReplaceAllString(s, "$1")
+	s = mdLink.ReplaceAllString(s, "$1")
+	s = mdBold.ReplaceAllString(s, "$2")
+	s = mdItalic.ReplaceAllString(s, "$1")
+	s = mdCode.ReplaceAllString(s, "$1")
+	s = mdHeading.ReplaceAllString(s, "")
+	s = mdQuote.ReplaceAllString(s, "")
+	s = mdBullet.ReplaceAllString(s, "$1• ")
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRightFunc(line, unicode.IsSpace)
+	}
+	s = strings.Join(lines, "\n")
+	s = blankLines.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
+
+// Text 將內容整理為可安全顯示的純文字
+func Text(s string) string {
+	return StripControl(PlainText(StripControl(s)))
+}
+
+// Fit 將字串限制在 max 個字元內；截斷時盡量停在字詞邊界並附加省略符號
+func Fit(s string, max int) string {
+	if max <= 0 || utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	if max == 1 {
+		return Ellipsis
+	}
+
+	runes := []rune(s)
+	cut := max - 1
+
+	// 在保留長度的最後 30% 內尋找空白或標點作為斷點，避免切斷單字
+	for i := cut; i > cut*7/10; i-- {
+		if unicode.IsSpace(runes[i]) || unicode.IsPunct(runes[i-1]) {
+			cut = i
+			break
+		}
+	}
+
+	head := strings.TrimRightFunc(string(runes[:cut]), func(r rune) bool {
+		return unicode.IsSpace(r) || r == ',' || r == '，' || r == '、'
+	})
+	return head + Ellipsis
+}
+
+// Prepare 將標題與內容整理為純文字並符合後端長度限制
+func Prepare(title, message string, limits Limits) (string, string) {
+	title = strings.Join(strings.Fields(Text(title)), " ")
+	return Fit(title, limits.Title), Fit(Text(message), limits.Message)
+}
+
+// toastEscaper 處理 go-toast 產生的 PowerShell 字串：內容位於 @"..."@ 中的 CDATA，
+// 反引號、$ 與雙引號需以反引號跳脫，]]> 需拆開以免提前結束 CDATA
+var toastEscaper = strings.NewReplacer(
+	"`", "``",
+	"$", "`$",
+	`"`, "`\"",
+	"]]>", "]]]]><![CDATA[>",
+)
+
+// EscapeToast 跳脫內容使其可安全嵌入 go-toast 的 PowerShell 腳本與 XML
+func EscapeToast(s string) string {
+	return toastEscaper.Replace(s)
+}
+
+// EscapeToastAttr 跳脫內容使其可安全放入 go-toast 的 XML 屬性（例如動作網址）
+func EscapeToastAttr(s string) string {
+	return EscapeToast(html.EscapeString(StripControl(s)))
+}
@@ -0,0 +1,120 @@
+package sanitize
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+func TestPlainText(t *testing.T) {
+	cases := []struct {
+		in, want string
+	}{
+		{"**Build** failed on `main`", "Build failed on main"},
+		{"## Deploy\n- step one\n- [log](https://ci/1)", "Deploy\n• step one\n• log"},
+		{"<p>Hello &amp; <b>bye</b></p><br/>next", "Hello & bye\n\nnext"},
+		{"free_youtube 2 * 3 = 6", "free_youtube 2 * 3 = 6"},
+		{"a &lt;b&gt; c", "a <b> c"},
+	}
+	for _, c := range cases {
+		if got := PlainText(c.in); got != c.want {
+			t.Errorf("PlainText(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestFit(t *testing.T) {
+	cases := []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"the quick brown fox jumps", 12, "the quick…"},
+		{"abcdefghijklmnop", 8, "abcdefg…"},
+		{"部署失敗，請查看日誌", 6, "部署失敗…"},
+		{"anything", 0, "anything"},
+	}
+	for _, c := range cases {
+		if got := Fit(c.in, c.max); got != c.want {
+			t.Errorf("Fit(%q, %d) = %q, want %q", c.in, c.max, got, c.want)
+		}
+	}
+}
+
+func TestEscapeToast(t *testing.T) {
+	got := EscapeToast("cost $5 `rm` \"x\" ]]> end")
+	want := "cost `$5 ``rm`` `\"x`\" ]]]]><![CDATA[> end"
+	if got != want {
+		t.Errorf("EscapeToast = %q, want %q", got, want)
+	}
+}
+
+func FuzzStripControl(f *testing.F) {
+	for _, seed := range []string{"plain", "a\x00b\x1bc", "line\r\nnext", "\u202eevil", "\xff\xfe"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		out := StripControl(s)
+		if !utf8.ValidString(out) {
+			t.Fatalf("invalid UTF-8 in %q", out)
+		}
+		for _, r := range out {
+			if r != '\n' && r != '\t' && (unicode.IsControl(r) || isBidiControl(r)) {
+				t.Fatalf("control rune %U left in %q", r, out)
+			}
+		}
+		if StripControl(out) != out {
+			t.Fatalf("StripControl not idempotent for %q", s)
+		}
+	})
+}
+
+func FuzzFit(f *testing.F) {
+	f.Add("the quick brown fox", 10)
+	f.Add("部署失敗，請查看日誌", 3)
+	f.Add("", 1)
+	f.Fuzz(func(t *testing.T, s string, max int) {
+		if max > 1<<12 || max < -1<<12 {
+			t.Skip()
+		}
+		s = StripControl(s)
+		out := Fit(s, max)
+		n := utf8.RuneCountInString(out)
+		if max > 0 && n > max {
+			t.Fatalf("Fit(%q, %d) = %q has %d runes", s, max, out, n)
+		}
+		if out != s {
+			head := strings.TrimSuffix(out, Ellipsis)
+			if !strings.HasSuffix(out, Ellipsis) || !strings.HasPrefix(s, head) {
+				t.Fatalf("Fit(%q, %d) = %q is not a prefix with ellipsis", s, max, out)
+			}
+		}
+	})
+}
+
+func FuzzPrepareToast(f *testing.F) {
+	f.Add("<b>title</b>", "**msg** with $env:PATH and `cmd` ]]>")
+	f.Add("\"@\nInjected", "@\"\n$(Remove-Item)")
+	f.Fuzz(func(t *testing.T, title, message string) {
+		title, message = Prepare(title, message, Limits{Title: 64, Message: 250})
+		if strings.Contains(title, "\n") {
+			t.Fatalf("title contains newline: %q", title)
+		}
+		for _, s := range []string{title, message} {
+			escaped := EscapeToast(s)
+			if strings.Contains(escaped, "]]>") && !strings.Contains(escaped, "]]]]><![CDATA[>") {
+				t.Fatalf("unescaped CDATA end in %q", escaped)
+			}
+			for i := 0; i < len(escaped); i++ {
+				switch escaped[i] {
+				case '`':
+					i++ // 跳脫字元後一律是被跳脫的字元
+				case '$', '"':
+					t.Fatalf("unescaped %q at %d in %q", escaped[i], i, escaped)
+				}
+			}
+		}
+	})
+}
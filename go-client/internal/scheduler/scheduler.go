@@ -0,0 +1,187 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"windows-notification/internal/api"
+	"windows-notification/internal/jsonfile"
+	"windows-notification/internal/logger"
+	"windows-notification/internal/quiethours"
+)
+
+// checkInterval 是檢查到期項目的間隔；以牆上時間比較，電腦睡眠錯過的項目會在喚醒後立即送出
+const checkInterval = 30 * time.Second
+
+// Choice 代表一個延後選項
+type Choice struct {
+	Label string // 按鈕文字
+	Delay string // 延後時間，格式同 ParseDelay
+}
+
+// Choices 是通知與視窗中提供的延後選項
+var Choices = []Choice{
+	{Label: "Snooze 10m", Delay: "10m"},
+	{Label: "Snooze 1h", Delay: "1h"},
+	{Label: "Tomorrow", Delay: "tomorrow"},
+}
+
+// Item 代表一則延後提醒的通知
+type Item struct {
+	Notification api.Notification `json:"notification"`
+	Notifier     string           `json:"notifier"`
+	SnoozedAt    time.Time        `json:"snoozed_at"`
+	DueAt        time.Time        `json:"due_at"`
+}
+
+// DeliverFunc 重新送出到期的通知
+type DeliverFunc func(item Item) error
+
+// Scheduler 持久化保存延後提醒的通知，並在到期時重新送出
+type Scheduler struct {
+	mu     sync.Mutex
+	path   string
+	items  map[string]*Item
+	wake   chan struct{}
+	logger *logger.Logger
+}
+
+// Open 開啟（或建立）延後提醒清單
+func Open(path string, log *logger.Logger) (*Scheduler, error) {
+	s := &Scheduler{
+		path:   path,
+		items:  make(map[string]*Item),
+		wake:   make(chan struct{}, 1),
+		logger: log,
+	}
+	if err := jsonfile.Load(path, &s.items); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ParseDelay 解析延後時間：Go duration（例如 10m、1h）或 tomorrow（明天早上）
+func ParseDelay(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if s == "tomorrow" {
+		return quiethours.TomorrowMorning(now), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return time.Time{}, fmt.Errorf("無效的延後時間 %q", s)
+	}
+	return now.Add(d), nil
+}
+
+// Snooze 延後通知到指定時間；已延後的通知會更新到期時間
+func (s *Scheduler) Snooze(n api.Notification, notifier string, due time.Time, now time.Time) error {
+	s.mu.Lock()
+	s.items[n.ID] = &Item{
+		Notification: n,
+		Notifier:     notifier,
+		SnoozedAt:    now,
+		DueAt:        due,
+	}
+	err := s.save()
+	s.mu.Unlock()
+
+	// 喚醒排程迴圈，讓極短的延後也能準時送出
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return err
+}
+
+// Contains 判斷通知是否正在延後中
+func (s *Scheduler) Contains(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.items[id]
+	return ok
+}
+
+// Cancel 取消延後提醒
+func (s *Scheduler) Cancel(id string) (Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[id]
+	if !ok {
+		return Item{}, fmt.Errorf("找不到延後的通知 %s", id)
+	}
+	delete(s.items, id)
+	return *item, s.save()
+}
+
+// List 返回所有延後中的通知（依到期時間排序）
+func (s *Scheduler) List() []Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := make([]Item, 0, len(s.items))
+	for _, item := range s.items {
+		items = append(items, *item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DueAt.Before(items[j].DueAt)
+	})
+	return items
+}
+
+// Run 定期送出到期的通知，直到 ctx 取消
+func (s *Scheduler) Run(ctx context.Context, deliver DeliverFunc) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		s.deliverDue(deliver)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// deliverDue 送出所有到期的通知，送出失敗的通知保留到下次檢查
+func (s *Scheduler) deliverDue(deliver DeliverFunc) {
+	// Round(0) 去除單調時鐘，改以牆上時間比較，睡眠期間經過的時間也會計入
+	now := time.Now().Round(0)
+
+	for _, item := range s.List() {
+		if item.DueAt.After(now) {
+			break
+		}
+
+		if err := deliver(item); err != nil {
+			if s.logger != nil {
+				s.logger.Errorf("送出延後的通知失敗 (ID: %s): %v", item.Notification.ID, err)
+			}
+			continue
+		}
+
+		s.mu.Lock()
+		// 送出期間可能再次被延後，只移除同一次的延後項目
+		if current, ok := s.items[item.Notification.ID]; ok && current.SnoozedAt.Equal(item.SnoozedAt) {
+			delete(s.items, item.Notification.ID)
+			if err := s.save(); err != nil && s.logger != nil {
+				s.logger.Warnf("儲存延後提醒清單失敗: %v", err)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// save 寫入磁碟（呼叫端需持有鎖）
+func (s *Scheduler) save() error {
+	return jsonfile.Save(s.path, s.items)
+}
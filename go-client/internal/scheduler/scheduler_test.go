@@ -0,0 +1,89 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"windows-notification/internal/api"
+)
+
+func TestOverdueDeliveredAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snoozed.json")
+	s, err := Open(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	s.Snooze(api.Notification{ID: "overdue"}, "pager", now.Add(-time.Hour), now.Add(-2*time.Hour))
+	s.Snooze(api.Notification{ID: "failing"}, "", now.Add(-time.Minute), now.Add(-2*time.Hour))
+	s.Snooze(api.Notification{ID: "later"}, "", now.Add(time.Hour), now)
+
+	// 重新開啟後仍保留延後清單，第一次檢查即送出錯過的項目
+	s, err = Open(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivered := make(chan Item, 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx, func(item Item) error {
+			delivered <- item
+			if item.Notification.ID == "failing" {
+				return errors.New("toast failed")
+			}
+			return nil
+		})
+		close(done)
+	}()
+
+	var got []string
+	for len(got) < 2 {
+		select {
+		case item := <-delivered:
+			got = append(got, item.Notification.ID)
+			if item.Notification.ID == "overdue" && item.Notifier != "pager" {
+				t.Errorf("notifier = %q, want pager", item.Notifier)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out, delivered %v", got)
+		}
+	}
+	cancel()
+	<-done
+
+	// 依到期時間送出；送出失敗的項目保留到下次檢查，未到期的不送出
+	if got[0] != "overdue" || got[1] != "failing" || len(delivered) != 0 {
+		t.Errorf("delivered %v, want overdue then failing", got)
+	}
+	if s.Contains("overdue") || !s.Contains("failing") || !s.Contains("later") {
+		t.Errorf("remaining = %+v", s.List())
+	}
+}
+
+func TestParseDelay(t *testing.T) {
+	now := time.Date(2024, 5, 1, 15, 0, 0, 0, time.Local)
+	cases := []struct {
+		delay string
+		want  time.Time
+		ok    bool
+	}{
+		{"10m", now.Add(10 * time.Minute), true},
+		{" 1H ", now.Add(time.Hour), true},
+		{"0s", time.Time{}, false},
+		{"-5m", time.Time{}, false},
+		{"soon", time.Time{}, false},
+	}
+	for _, c := range cases {
+		got, err := ParseDelay(c.delay, now)
+		if (err == nil) != c.ok || !got.Equal(c.want) {
+			t.Errorf("ParseDelay(%q) = %v, %v", c.delay, got, err)
+		}
+	}
+	if due, err := ParseDelay("tomorrow", now); err != nil || !due.After(now) || due.Day() != 2 {
+		t.Errorf("ParseDelay(tomorrow) = %v, %v", due, err)
+	}
+}
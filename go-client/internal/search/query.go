@@ -0,0 +1,190 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"windows-notification/internal/history"
+)
+
+// Term 代表一個搜尋詞
+type Term struct {
+	Text   string // 已正規化的詞
+	Prefix bool   // 以 * 結尾時比對前綴
+	Field  Field  // 限定欄位，AnyField 代表全部欄位
+}
+
+// Query 代表解析後的搜尋條件
+type Query struct {
+	Terms    []Term         // 必須全部符合的詞
+	Exclude  []Term         // 不可出現的詞（-詞）
+	Phrases  []string       // 必須完整出現的片語（"..."）
+	Filter   history.Filter // 處理結果與時間範圍
+	Project  string         // 專案（支援 * 萬用字元）
+	Repo     string         // 倉庫（支援 * 萬用字元）
+	Branch   string         // 分支（支援 * 萬用字元）
+	Type     string         // 類型（支援 * 萬用字元）
+	Priority string         // 優先權（支援 * 萬用字元）
+}
+
+// Parse 解析搜尋語法，例如 `project:crm failed since:7d`：
+//
+//	詞             標題、內容、專案、倉庫、分支中必須出現的詞，詞尾加 * 比對前綴
+//	"片語"         必須完整出現的片語
+//	-詞            不可出現的詞
+//	title:詞       只比對標題（message: 只比對內容）
+//	project:名稱   專案，支援 * 萬用字元（repo:、branch:、type:、priority: 相同）
+//	status:結果    處理結果，例如 displayed、suppressed（outcome: 相同）
+//	since:7d       收到時間下限，可為 30m、12h、7d、2w 或 2006-01-02
+//	until:日期     收到時間上限，格式同 since
+func Parse(q string, now time.Time) (Query, error) {
+	var query Query
+	for _, word := range splitQuery(q) {
+		if word == "" {
+			continue
+		}
+
+		if strings.HasPrefix(word, "-") && len(word) > 1 {
+			query.Exclude = append(query.Exclude, terms(unquote(word[1:]), AnyField)...)
+			continue
+		}
+
+		key, value, ok := strings.Cut(word, ":")
+		if !ok || value == "" {
+			query.addText(word, AnyField)
+			continue
+		}
+		raw := value
+		value = unquote(value)
+
+		switch strings.ToLower(key) {
+		case "project":
+			query.Project = strings.ToLower(value)
+		case "repo":
+			query.Repo = strings.ToLower(value)
+		case "branch":
+			query.Branch = strings.ToLower(value)
+		case "type":
+			query.Type = strings.ToLower(value)
+		case "priority":
+			query.Priority = strings.ToLower(value)
+		case "status", "outcome":
+			query.Filter.Outcomes = append(query.Filter.Outcomes, history.Outcome(strings.ToLower(value)))
+		case "since":
+			t, err := parseTime(value, now)
+			if err != nil {
+				return Query{}, fmt.Errorf("since 無效: %w", err)
+			}
+			query.Filter.Since = t
+		case "until":
+			t, err := parseTime(value, now)
+			if err != nil {
+				return Query{}, fmt.Errorf("until 無效: %w", err)
+			}
+			query.Filter.Until = t
+		case "title":
+			query.addText(raw, FieldTitle)
+		case "message":
+			query.addText(raw, FieldMessage)
+		default:
+			// 未知的欄位視為一般文字，例如 error:timeout
+			query.addText(word, AnyField)
+		}
+	}
+	return query, nil
+}
+
+// Empty 判斷是否沒有任何條件
+func (q Query) Empty() bool {
+	f := q.Filter
+	return len(q.Terms) == 0 && len(q.Exclude) == 0 && len(q.Phrases) == 0 &&
+		len(f.Outcomes) == 0 && f.Since.IsZero() && f.Until.IsZero() &&
+		q.Project == "" && q.Repo == "" && q.Branch == "" && q.Type == "" && q.Priority == ""
+}
+
+// addText 加入一般文字；含空白的引號文字視為片語
+func (q *Query) addText(word string, field Field) {
+	quoted := strings.HasPrefix(word, `"`)
+	word = unquote(word)
+	if quoted && strings.ContainsFunc(word, unicode.IsSpace) {
+		q.Phrases = append(q.Phrases, strings.ToLower(word))
+	}
+	q.Terms = append(q.Terms, terms(word, field)...)
+}
+
+// terms 將文字切成搜尋詞，結尾的 * 代表前綴比對
+func terms(text string, field Field) []Term {
+	prefix := strings.HasSuffix(text, "*")
+	tokens := Tokenize(strings.TrimSuffix(text, "*"))
+
+	result := make([]Term, 0, len(tokens))
+	for i, tok := range tokens {
+		result = append(result, Term{
+			Text:   tok.Text,
+			Prefix: prefix && i == len(tokens)-1,
+			Field:  field,
+		})
+	}
+	return result
+}
+
+// splitQuery 以空白切分搜尋語法，引號內的空白不切分
+func splitQuery(q string) []string {
+	var words []string
+	var current strings.Builder
+	inQuote := false
+
+	for _, r := range q {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			current.WriteRune(r)
+		case unicode.IsSpace(r) && !inQuote:
+			words = append(words, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	return append(words, current.String())
+}
+
+// unquote 移除前後的引號
+func unquote(s string) string {
+	return strings.Trim(s, `"`)
+}
+
+// parseTime 解析相對時間（30m、12h、7d、2w）或本機日期（2006-01-02、2006-01-02T15:04）
+func parseTime(value string, now time.Time) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	if len(value) < 2 {
+		return time.Time{}, fmt.Errorf("無法解析 %q", value)
+	}
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n < 0 {
+		return time.Time{}, fmt.Errorf("無法解析 %q", value)
+	}
+
+	var unit time.Duration
+	switch value[len(value)-1] {
+	case 'm':
+		unit = time.Minute
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	default:
+		return time.Time{}, fmt.Errorf("無法解析 %q，單位需為 m、h、d 或 w", value)
+	}
+	return now.Add(-time.Duration(n) * unit), nil
+}
@@ -0,0 +1,389 @@
+package search
+
+import (
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"windows-notification/internal/history"
+)
+
+// Field 代表可搜尋的欄位
+type Field int
+
+const (
+	AnyField Field = iota - 1
+	FieldTitle
+	FieldMessage
+	FieldProject
+	FieldRepo
+	FieldBranch
+	fieldCount
+)
+
+// boosts 是各欄位命中時的權重，標題最重要
+var boosts = [fieldCount]float64{
+	FieldTitle:   3,
+	FieldMessage: 1,
+	FieldProject: 2,
+	FieldRepo:    2,
+	FieldBranch:  2,
+}
+
+// snippetRunes 是內容摘錄的最大字數
+const snippetRunes = 160
+
+// Token 代表文字中的一個詞與其位置（位元組偏移）
+type Token struct {
+	Text       string
+	Start, End int
+}
+
+// Tokenize 將文字切成小寫的詞；英數字以連續字元為一詞，
+// 中日韓文字以相鄰兩字為一詞，單獨一字時以單字為詞
+func Tokenize(s string) []Token {
+	return tokenize(s, false)
+}
+
+// indexTokens 切出建立索引用的詞；中日韓文字另外以單字為詞，讓一個字的搜尋也能找到較長的詞
+func indexTokens(s string) []Token {
+	return tokenize(s, true)
+}
+
+// tokenize 實作 Tokenize；unigrams 為 true 時連續的中日韓文字同時輸出每個單字
+func tokenize(s string, unigrams bool) []Token {
+	var tokens []Token
+	start := -1
+	var han []int // 連續中日韓文字的位元組偏移
+
+	flushWord := func(end int) {
+		if start >= 0 {
+			tokens = append(tokens, Token{Text: strings.ToLower(s[start:end]), Start: start, End: end})
+			start = -1
+		}
+	}
+	flushHan := func(end int) {
+		switch len(han) {
+		case 0:
+			return
+		case 1:
+			tokens = append(tokens, Token{Text: s[han[0]:end], Start: han[0], End: end})
+		default:
+			han = append(han, end)
+			for i := 0; i+1 < len(han); i++ {
+				if unigrams {
+					tokens = append(tokens, Token{Text: s[han[i]:han[i+1]], Start: han[i], End: han[i+1]})
+				}
+				if i+2 < len(han) {
+					tokens = append(tokens, Token{Text: s[han[i]:han[i+2]], Start: han[i], End: han[i+2]})
+				}
+			}
+		}
+		han = han[:0]
+	}
+
+	for i, r := range s {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) || isProlonged(r):
+			flushWord(i)
+			han = append(han, i)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHan(i)
+			if start < 0 {
+				start = i
+			}
+		default:
+			flushWord(i)
+			flushHan(i)
+		}
+	}
+	flushWord(len(s))
+	flushHan(len(s))
+	return tokens
+}
+
+// isProlonged 判斷是否為長音符號（ー、ｰ）；它不屬於片假名字集，但出現在片假名詞中
+func isProlonged(r rune) bool {
+	return r == '\u30fc' || r == '\uff70'
+}
+
+// Span 代表一段文字，Match 為命中搜尋詞的部分
+type Span struct {
+	Text  string
+	Match bool
+}
+
+// Result 代表一筆搜尋結果
+type Result struct {
+	Record  history.Record
+	Score   float64
+	Title   []Span // 標示命中詞的標題
+	Snippet []Span // 標示命中詞的內容摘錄
+}
+
+// Index 是通知歷史的全文索引
+type Index struct {
+	docs     []history.Record
+	postings map[string]map[int]*[fieldCount]int // 詞 -> 文件 -> 各欄位出現次數
+	terms    []string                            // 排序後的詞，供前綴比對
+}
+
+// Build 以歷史記錄建立索引
+func Build(records []history.Record) *Index {
+	idx := &Index{
+		docs:     records,
+		postings: make(map[string]map[int]*[fieldCount]int),
+	}
+	for doc, r := range records {
+		for field, text := range fields(r) {
+			for _, tok := range indexTokens(text) {
+				docs, ok := idx.postings[tok.Text]
+				if !ok {
+					docs = make(map[int]*[fieldCount]int)
+					idx.postings[tok.Text] = docs
+				}
+				tf, ok := docs[doc]
+				if !ok {
+					tf = new([fieldCount]int)
+					docs[doc] = tf
+				}
+				tf[field]++
+			}
+		}
+	}
+
+	idx.terms = make([]string, 0, len(idx.postings))
+	for term := range idx.postings {
+		idx.terms = append(idx.terms, term)
+	}
+	sort.Strings(idx.terms)
+	return idx
+}
+
+// Len 返回索引中的文件數
+func (idx *Index) Len() int {
+	return len(idx.docs)
+}
+
+// Search 返回符合條件的結果，依相關程度排序，分數相同時較新的在前；limit 為 0 不限制
+func (idx *Index) Search(q Query, limit int) []Result {
+	var results []Result
+	for doc, r := range idx.docs {
+		if !q.matchesFilters(r) {
+			continue
+		}
+
+		score, ok := idx.score(doc, q.Terms)
+		if !ok || idx.excluded(doc, q.Exclude) || !containsPhrases(r, q.Phrases) {
+			continue
+		}
+
+		results = append(results, Result{
+			Record:  r,
+			Score:   score,
+			Title:   Highlight(r.Notification.Title, q.Terms, 0),
+			Snippet: Highlight(r.Notification.Message, q.Terms, snippetRunes),
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Record.ReceivedAt.After(results[j].Record.ReceivedAt)
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// score 以 BM25 的詞頻飽和與 IDF 計算文件分數，任一詞未出現時返回 false
+func (idx *Index) score(doc int, terms []Term) (float64, bool) {
+	total := 0.0
+	n := float64(len(idx.docs))
+	for _, term := range terms {
+		df, tf := 0, 0.0
+		for _, text := range idx.expand(term) {
+			docs := idx.postings[text]
+			df += len(docs)
+			if counts, ok := docs[doc]; ok {
+				tf += weight(counts, term.Field)
+			}
+		}
+		if tf == 0 {
+			return 0, false
+		}
+		idf := math.Log(1 + (n-float64(df)+0.5)/(float64(df)+0.5))
+		total += idf * tf / (tf + 1.2)
+	}
+	return total, true
+}
+
+// excluded 判斷文件是否包含任一排除詞
+func (idx *Index) excluded(doc int, terms []Term) bool {
+	for _, term := range terms {
+		for _, text := range idx.expand(term) {
+			if counts, ok := idx.postings[text][doc]; ok && weight(counts, term.Field) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// expand 返回搜尋詞對應的索引詞，前綴詞會展開為所有符合的詞
+func (idx *Index) expand(term Term) []string {
+	if !term.Prefix {
+		return []string{term.Text}
+	}
+	i := sort.SearchStrings(idx.terms, term.Text)
+	j := i
+	for j < len(idx.terms) && strings.HasPrefix(idx.terms[j], term.Text) {
+		j++
+	}
+	return idx.terms[i:j]
+}
+
+// weight 依欄位權重加總詞頻
+func weight(counts *[fieldCount]int, field Field) float64 {
+	if field != AnyField {
+		return boosts[field] * float64(counts[field])
+	}
+	w := 0.0
+	for f, c := range counts {
+		w += boosts[f] * float64(c)
+	}
+	return w
+}
+
+// fields 返回記錄中可搜尋的欄位
+func fields(r history.Record) [fieldCount]string {
+	n := r.Notification
+	return [fieldCount]string{
+		FieldTitle:   n.Title,
+		FieldMessage: n.Message,
+		FieldProject: n.Project,
+		FieldRepo:    n.Repo,
+		FieldBranch:  n.Branch,
+	}
+}
+
+// matchesFilters 判斷記錄是否符合欄位篩選條件
+func (q Query) matchesFilters(r history.Record) bool {
+	n := r.Notification
+	return q.Filter.Matches(r) &&
+		globMatch(q.Project, n.Project) &&
+		globMatch(q.Repo, n.Repo) &&
+		globMatch(q.Branch, n.Branch) &&
+		globMatch(q.Type, n.Type) &&
+		globMatch(q.Priority, n.Priority)
+}
+
+// globMatch 以不分大小寫的萬用字元比對，空的樣式代表全部符合
+func globMatch(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, strings.ToLower(value))
+	return ok
+}
+
+// containsPhrases 判斷記錄是否完整包含所有片語
+func containsPhrases(r history.Record, phrases []string) bool {
+	if len(phrases) == 0 {
+		return true
+	}
+	text := fields(r)
+	all := strings.ToLower(strings.Join(text[:], "\n"))
+	for _, phrase := range phrases {
+		if !strings.Contains(all, phrase) {
+			return false
+		}
+	}
+	return true
+}
+
+// Highlight 將文字切成一般與命中搜尋詞的片段；maxRunes 大於 0 時
+// 只保留第一個命中詞附近的摘錄，前後被截斷時加上省略號
+func Highlight(text string, terms []Term, maxRunes int) []Span {
+	var ranges [][2]int
+	for _, tok := range indexTokens(text) {
+		for _, term := range terms {
+			if tok.Text == term.Text || (term.Prefix && strings.HasPrefix(tok.Text, term.Text)) {
+				if n := len(ranges); n > 0 && tok.Start <= ranges[n-1][1] {
+					ranges[n-1][1] = max(ranges[n-1][1], tok.End)
+				} else {
+					ranges = append(ranges, [2]int{tok.Start, tok.End})
+				}
+				break
+			}
+		}
+	}
+
+	from, to := 0, len(text)
+	if maxRunes > 0 && utf8.RuneCountInString(text) > maxRunes {
+		if len(ranges) > 0 {
+			from = backRunes(text, ranges[0][0], maxRunes/4)
+		}
+		to = forwardRunes(text, from, maxRunes)
+	}
+
+	var spans []Span
+	if from > 0 {
+		spans = append(spans, Span{Text: "…"})
+	}
+	pos := from
+	for _, rg := range ranges {
+		start, end := max(rg[0], from), min(rg[1], to)
+		if start >= end {
+			continue
+		}
+		if start > pos {
+			spans = append(spans, Span{Text: text[pos:start]})
+		}
+		spans = append(spans, Span{Text: text[start:end], Match: true})
+		pos = end
+	}
+	if pos < to {
+		spans = append(spans, Span{Text: text[pos:to]})
+	}
+	if to < len(text) {
+		spans = append(spans, Span{Text: "…"})
+	}
+	return spans
+}
+
+// backRunes 從位元組偏移往前移動 n 個字
+func backRunes(s string, pos, n int) int {
+	for ; n > 0 && pos > 0; n-- {
+		_, size := utf8.DecodeLastRuneInString(s[:pos])
+		pos -= size
+	}
+	return pos
+}
+
+// forwardRunes 從位元組偏移往後移動 n 個字
+func forwardRunes(s string, pos, n int) int {
+	for ; n > 0 && pos < len(s); n-- {
+		_, size := utf8.DecodeRuneInString(s[pos:])
+		pos += size
+	}
+	return pos
+}
+
+// Plain 將片段組回文字，命中的部分以 mark 包住
+func Plain(spans []Span, mark string) string {
+	var b strings.Builder
+	for _, s := range spans {
+		if s.Match {
+			b.WriteString(mark + s.Text + mark)
+		} else {
+			b.WriteString(s.Text)
+		}
+	}
+	return b.String()
+}
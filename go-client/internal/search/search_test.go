@@ -0,0 +1,166 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"windows-notification/internal/api"
+	"windows-notification/internal/history"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name    string
+		query   string
+		check   func(q Query) bool
+		wantErr bool
+	}{
+		{"terms", "Deploy failed", func(q Query) bool {
+			return reflect.DeepEqual(q.Terms, []Term{{Text: "deploy", Field: AnyField}, {Text: "failed", Field: AnyField}})
+		}, false},
+		{"quoted phrase", `"build failed" nightly`, func(q Query) bool {
+			return reflect.DeepEqual(q.Phrases, []string{"build failed"}) && len(q.Terms) == 3
+		}, false},
+		{"quoted single word is a term", `"timeout"`, func(q Query) bool {
+			return len(q.Phrases) == 0 && len(q.Terms) == 1 && q.Terms[0].Text == "timeout"
+		}, false},
+		{"prefix and exclude", "deplo* -staging", func(q Query) bool {
+			return q.Terms[0] == Term{Text: "deplo", Prefix: true, Field: AnyField} &&
+				reflect.DeepEqual(q.Exclude, []Term{{Text: "staging", Field: AnyField}})
+		}, false},
+		{"field filters", "project:CRM repo:web-* branch:main type:ci priority:high status:displayed", func(q Query) bool {
+			return q.Project == "crm" && q.Repo == "web-*" && q.Branch == "main" && q.Type == "ci" && q.Priority == "high" &&
+				reflect.DeepEqual(q.Filter.Outcomes, []history.Outcome{history.OutcomeDisplayed})
+		}, false},
+		{"field terms", `title:"disk full" message:retry`, func(q Query) bool {
+			return reflect.DeepEqual(q.Terms, []Term{{Text: "disk", Field: FieldTitle}, {Text: "full", Field: FieldTitle}, {Text: "retry", Field: FieldMessage}}) &&
+				reflect.DeepEqual(q.Phrases, []string{"disk full"})
+		}, false},
+		{"unknown field is text", "error:timeout", func(q Query) bool {
+			return len(q.Terms) == 2 && q.Terms[0].Text == "error" && q.Terms[1].Text == "timeout"
+		}, false},
+		{"relative since", "since:7d", func(q Query) bool {
+			return q.Filter.Since.Equal(now.Add(-7 * 24 * time.Hour))
+		}, false},
+		{"date range", "since:2024-05-01 until:2024-05-02T08:30", func(q Query) bool {
+			return q.Filter.Since.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) &&
+				q.Filter.Until.Equal(time.Date(2024, 5, 2, 8, 30, 0, 0, time.UTC))
+		}, false},
+		{"bad unit", "since:7y", nil, true},
+		{"bad date", "until:2024-13-01", nil, true},
+		{"negative", "since:-3d", nil, true},
+		{"empty", "  ", func(q Query) bool { return q.Empty() }, false},
+	}
+	for _, c := range cases {
+		q, err := Parse(c.query, now)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: Parse(%q) succeeded, want error", c.name, c.query)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Parse(%q) = %v", c.name, c.query, err)
+			continue
+		}
+		if !c.check(q) {
+			t.Errorf("%s: Parse(%q) = %+v", c.name, c.query, q)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	cases := []struct {
+		text string
+		want []string
+	}{
+		{"Build FAILED on main", []string{"build", "failed", "on", "main"}},
+		{"部署失敗", []string{"部署", "署失", "失敗"}},
+		{"v2.3 部署完成OK", []string{"v2", "3", "部署", "署完", "完成", "ok"}},
+		{"CI失敗: 單", []string{"ci", "失敗", "單"}},
+		{"データベース", []string{"デー", "ータ", "タベ", "ベー", "ース"}},
+		{"", nil},
+	}
+	for _, c := range cases {
+		var got []string
+		for _, tok := range Tokenize(c.text) {
+			if c.text[tok.Start:tok.End] == "" {
+				t.Errorf("Tokenize(%q): empty span for %q", c.text, tok.Text)
+			}
+			got = append(got, tok.Text)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", c.text, got, c.want)
+		}
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	record := func(id, project, title, message string, age int) history.Record {
+		return history.Record{
+			ID:           id,
+			Notification: api.Notification{ID: id, Project: project, Title: title, Message: message},
+			ReceivedAt:   base.Add(-time.Duration(age) * time.Hour),
+		}
+	}
+	idx := Build([]history.Record{
+		record("message", "web", "Nightly report", "the deploy step failed", 1),
+		record("title", "web", "Deploy failed", "see logs", 2),
+		record("newer", "web", "Deploy failed", "see logs", 0),
+		record("other", "api", "Backup done", "all good", 0),
+		record("zh", "crm", "部署失敗", "資料庫連線逾時", 3),
+	})
+
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		// 標題權重較高，分數相同時較新的在前
+		{"deploy failed", []string{"newer", "title", "message"}},
+		{"deplo*", []string{"newer", "title", "message"}},
+		{"deploy -logs", []string{"message"}},
+		{`"deploy step"`, []string{"message"}},
+		{"title:report", []string{"message"}},
+		{"project:api", []string{"other"}},
+		{"失敗", []string{"zh"}},
+		{"敗", []string{"zh"}},
+		{"庫", []string{"zh"}},
+		{"title:庫", nil},
+		{"資料庫 逾時", []string{"zh"}},
+		{"missing", nil},
+	}
+	for _, c := range cases {
+		q, err := Parse(c.query, base)
+		if err != nil {
+			t.Fatalf("Parse(%q) = %v", c.query, err)
+		}
+		var got []string
+		for _, r := range idx.Search(q, 0) {
+			got = append(got, r.Record.ID)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Search(%q) = %v, want %v", c.query, got, c.want)
+		}
+	}
+}
+
+func TestSingleCharacterQuery(t *testing.T) {
+	var got []string
+	for _, tok := range indexTokens("發生錯誤") {
+		got = append(got, tok.Text)
+	}
+	if want := []string{"發", "發生", "生", "生錯", "錯", "錯誤", "誤"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("indexTokens() = %q, want %q", got, want)
+	}
+
+	q, err := Parse("錯", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Plain(Highlight("發生錯誤，請重試", q.Terms, 0), "*"); got != "發生*錯*誤，請重試" {
+		t.Errorf("Highlight() = %q", got)
+	}
+}
@@ -1,11 +1,43 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"os"
+
+	"windows-notification/internal/cli"
 	"windows-notification/internal/gui"
+	"windows-notification/internal/instance"
 )
 
 func main() {
+	// 帶子命令時以命令列模式執行
+	if cli.IsCommand(os.Args[1:]) {
+		os.Exit(cli.Run(os.Args[1:]))
+	}
+
+	// 相同設定檔只允許一個監控程式，重複啟動時轉交給執行中的程式（預設顯示視窗）
+	lockPath := instance.Path("config.json")
+	lock, err := instance.Acquire(lockPath)
+	var running *instance.RunningError
+	if errors.As(err, &running) {
+		if _, err := instance.Forward(lockPath, os.Args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "警告: %v，未啟用單一執行個體檢查\n", err)
+	}
+
 	// 建立並執行 GUI 應用程式
 	window := gui.NewAppWindow()
+	if lock != nil {
+		lock.Handle(window.HandleForward)
+	}
 	window.Run()
+	if lock != nil {
+		lock.Release()
+	}
 }